@@ -4,6 +4,8 @@ package ast
 import (
 	"bytes"
 	"golox/token"
+	"path/filepath"
+	"strings"
 )
 
 // AST Node
@@ -17,6 +19,37 @@ type Stmt interface {
 	statementNode()
 }
 
+// StmtToken returns the first token of a statement, which holds its position
+func StmtToken(stmt Stmt) token.Token {
+	switch stmt := stmt.(type) {
+	case *ExprStmt:
+		return stmt.Token
+	case *PrintStmt:
+		return stmt.Token
+	case *AssignStmt:
+		return stmt.Name.Token
+	case *SetStmt:
+		return stmt.Token
+	case *FuncDeclStmt:
+		return stmt.Token
+	case *VarStmt:
+		return stmt.Token
+	case *BlockStmt:
+		return stmt.Token
+	case *IfStmt:
+		return stmt.Token
+	case *WhileStmt:
+		return stmt.Token
+	case *ReturnStmt:
+		return stmt.Token
+	case *DebuggerStmt:
+		return stmt.Token
+	case *ImportStmt:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
 // Program is a list of statements
 type Program struct {
 	Statements []Stmt
@@ -25,11 +58,20 @@ type Program struct {
 func (p Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
-		out.WriteString(s.String())
+		out.WriteString(str(s))
 	}
 	return out.String()
 }
 
+// Returns the string of a child node, which is empty
+// if the node is missing because of a parse error
+func str(node Node) string {
+	if isNil(node) {
+		return ""
+	}
+	return node.String()
+}
+
 // Expression node in the AST
 type Expr interface {
 	Node
@@ -45,7 +87,7 @@ type ExprStmt struct {
 func (es ExprStmt) statementNode() {}
 func (es ExprStmt) String() string {
 	es.statementNode()
-	return es.Expr.String()
+	return str(es.Expr)
 }
 
 // Print Statement
@@ -57,7 +99,7 @@ type PrintStmt struct {
 func (ps PrintStmt) statementNode() {}
 func (ps PrintStmt) String() string {
 	ps.statementNode()
-	return "print " + ps.Expr.String() + ";"
+	return "print " + str(ps.Expr) + ";"
 }
 
 // Assignment Statement
@@ -69,15 +111,47 @@ type AssignStmt struct {
 func (as AssignStmt) statementNode() {}
 func (as AssignStmt) String() string {
 	as.statementNode()
-	return as.Name.String() + " = " + as.Expr.String() + ";"
+	return as.Name.String() + " = " + str(as.Expr) + ";"
+}
+
+// Property assignment statement in the form 'OBJECT.NAME = EXPR;'
+type SetStmt struct {
+	Token  token.Token // first token of the object expression
+	Object Expr
+	Name   *Identifier
+	Value  Expr
+}
+
+func (ss SetStmt) statementNode() {}
+func (ss SetStmt) String() string {
+	ss.statementNode()
+	return str(ss.Object) + "." + str(ss.Name) + " = " + str(ss.Value) + ";"
 }
 
 // Function Declaration Statement
 type FuncDeclStmt struct {
-	Token  token.Token // first token of expression
-	Name   *Identifier
-	Params []*Identifier
-	Body   *BlockStmt
+	Token      token.Token // first token of expression
+	Name       *Identifier
+	Params     []*Identifier
+	ParamTypes []*Identifier // type annotation of each parameter, nil if it has none
+	ReturnType *Identifier   // nil if not annotated
+	Body       *BlockStmt
+}
+
+// ParamType returns the type annotation of the i'th parameter, or nil if it has none
+func (fs FuncDeclStmt) ParamType(i int) *Identifier {
+	if i < len(fs.ParamTypes) {
+		return fs.ParamTypes[i]
+	}
+	return nil
+}
+
+// Annotation returns a type annotation as written in source, or "" if there is none
+func Annotation(typ *Identifier) string {
+	if typ == nil {
+		return ""
+	}
+	return ": " + typ.String()
 }
 
 func (fs FuncDeclStmt) statementNode() {}
@@ -85,16 +159,19 @@ func (fs FuncDeclStmt) String() string {
 	fs.statementNode()
 	var out bytes.Buffer
 	out.WriteString("fun ")
-	out.WriteString(fs.Name.String())
+	out.WriteString(str(fs.Name))
 	out.WriteString("(")
 	for i, p := range fs.Params {
-		out.WriteString(p.String())
+		out.WriteString(str(p))
+		out.WriteString(Annotation(fs.ParamType(i)))
 		if i < len(fs.Params)-1 {
 			out.WriteString(", ")
 		}
 	}
-	out.WriteString(") ")
-	out.WriteString(fs.Body.String())
+	out.WriteString(")")
+	out.WriteString(Annotation(fs.ReturnType))
+	out.WriteString(" ")
+	out.WriteString(str(fs.Body))
 
 	return out.String()
 }
@@ -161,7 +238,7 @@ func (p PrefixExpr) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(p.Token.Lexeme) // operator
-	out.WriteString(p.Right.String())
+	out.WriteString(str(p.Right))
 	out.WriteString(")")
 
 	return out.String()
@@ -177,10 +254,10 @@ func (ce CallExpr) expressionNode() {}
 func (ce CallExpr) String() string {
 	ce.expressionNode()
 	var out bytes.Buffer
-	out.WriteString(ce.Function.String())
+	out.WriteString(str(ce.Function))
 	out.WriteString("(")
 	for i, a := range ce.Args {
-		out.WriteString(a.String())
+		out.WriteString(str(a))
 		if i < len(ce.Args)-1 {
 			out.WriteString(", ")
 		}
@@ -190,6 +267,46 @@ func (ce CallExpr) String() string {
 	return out.String()
 }
 
+// Property access in the form 'OBJECT.NAME'
+type GetExpr struct {
+	Token  token.Token // DOT token
+	Object Expr
+	Name   *Identifier
+}
+
+func (ge GetExpr) expressionNode() {}
+func (ge GetExpr) String() string {
+	ge.expressionNode()
+	return str(ge.Object) + "." + str(ge.Name)
+}
+
+// Method call in the form 'OBJECT.NAME(ARGS)'
+type MethodCallExpr struct {
+	Token  token.Token // method name token
+	Object Expr
+	Name   *Identifier
+	Args   []Expr
+}
+
+func (mc MethodCallExpr) expressionNode() {}
+func (mc MethodCallExpr) String() string {
+	mc.expressionNode()
+	var out bytes.Buffer
+	out.WriteString(str(mc.Object))
+	out.WriteString(".")
+	out.WriteString(str(mc.Name))
+	out.WriteString("(")
+	for i, a := range mc.Args {
+		out.WriteString(str(a))
+		if i < len(mc.Args)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type InfixExpr struct {
 	Left  Expr
 	Token token.Token // binary operator token
@@ -201,20 +318,21 @@ func (ie InfixExpr) String() string {
 	ie.expressionNode()
 	var out bytes.Buffer
 	out.WriteString("(")
-	out.WriteString(ie.Left.String())
+	out.WriteString(str(ie.Left))
 	out.WriteString(" ")
 	out.WriteString(ie.Token.Lexeme) // operator
 	out.WriteString(" ")
-	out.WriteString(ie.Right.String())
+	out.WriteString(str(ie.Right))
 	out.WriteString(")")
 
 	return out.String()
 }
 
-// Var Statement in the form of 'var IDENT = EXPR'
+// Var Statement in the form of 'var IDENT = EXPR' or 'var IDENT: TYPE = EXPR'
 type VarStmt struct {
 	Token token.Token // VAR token
 	Name  *Identifier
+	Type  *Identifier // nil if not annotated
 	Value Expr
 }
 
@@ -224,7 +342,8 @@ func (vs VarStmt) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(vs.Token.Lexeme + " ")
-	out.WriteString(vs.Name.String())
+	out.WriteString(str(vs.Name))
+	out.WriteString(Annotation(vs.Type))
 
 	// TODO: remove nil check
 	if vs.Value != nil {
@@ -238,6 +357,7 @@ func (vs VarStmt) String() string {
 type BlockStmt struct {
 	Token      token.Token // { token
 	Statements []Stmt
+	EndToken   token.Token // } token
 }
 
 func (bs *BlockStmt) statementNode() {}
@@ -246,7 +366,7 @@ func (bs BlockStmt) String() string {
 
 	out.WriteString("{")
 	for _, s := range bs.Statements {
-		out.WriteString(s.String())
+		out.WriteString(str(s))
 	}
 	out.WriteString("}")
 	return out.String()
@@ -266,9 +386,9 @@ func (ifs IfStmt) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("if ")
-	out.WriteString(ifs.Cond.String())
+	out.WriteString(str(ifs.Cond))
 	out.WriteString(" ")
-	out.WriteString(ifs.OnTrue.String())
+	out.WriteString(str(ifs.OnTrue))
 
 	if ifs.OnFalse != nil {
 		out.WriteString(" else ")
@@ -290,9 +410,9 @@ func (ws WhileStmt) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("while ")
-	out.WriteString(ws.Cond.String())
+	out.WriteString(str(ws.Cond))
 	out.WriteString(" ")
-	out.WriteString(ws.Body.String())
+	out.WriteString(str(ws.Body))
 
 	return out.String()
 }
@@ -317,3 +437,57 @@ func (rs ReturnStmt) String() string {
 
 	return out.String()
 }
+
+// Debugger statement in the form 'debugger;', pausing an attached debugger
+type DebuggerStmt struct {
+	Token token.Token // debugger token
+}
+
+func (ds DebuggerStmt) statementNode() {}
+func (ds DebuggerStmt) String() string {
+	ds.statementNode()
+	return "debugger;"
+}
+
+// Import statement in the form 'import "PATH";' or 'import "PATH" as NAME;',
+// declaring the module at PATH as a namespace of its globals
+type ImportStmt struct {
+	Token token.Token // import token
+	Path  token.Token // STRING token of the module's path
+	Alias *Identifier // nil if the module is named after its file
+}
+
+func (is ImportStmt) statementNode() {}
+func (is ImportStmt) String() string {
+	is.statementNode()
+	var out bytes.Buffer
+	out.WriteString("import " + is.Path.Lexeme)
+	if is.Alias != nil {
+		out.WriteString(" as " + is.Alias.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// ModulePath returns the path of the imported module, as written
+func (is ImportStmt) ModulePath() string {
+	path, _ := is.Path.Literal.(string)
+	return path
+}
+
+// Name returns the identifier the module is declared as: its alias,
+// or else the name of its file at the position of the path
+func (is ImportStmt) Name() *Identifier {
+	if is.Alias != nil {
+		return is.Alias
+	}
+	tok := token.NewToken(token.IDENTIFIER, ModuleName(is.ModulePath()), is.Path.Line, is.Path.LineOffset, nil)
+	return &Identifier{Token: tok}
+}
+
+// ModuleName returns the name of the file at path without its extension,
+// which a module imported without an alias is declared as
+func ModuleName(path string) string {
+	base := filepath.Base(filepath.FromSlash(path))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
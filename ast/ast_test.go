@@ -327,3 +327,30 @@ func TestAssignProgString(t *testing.T) {
 			expectedStr, assignProg)
 	}
 }
+
+// Left behind by the parser for statements and expressions with errors
+var missingNodesProg *Program = &Program{
+	Statements: []Stmt{
+		(*PrintStmt)(nil),
+		&PrintStmt{
+			Token: token.Token{Type: token.PRINT, Lexeme: "print"},
+			Expr: &InfixExpr{
+				Left: NumExpr{
+					Token: token.Token{Type: token.NUMBER, Lexeme: "1", Literal: 1.0},
+				},
+				Token: token.Token{Type: token.PLUS, Lexeme: "+"},
+			},
+		},
+		&WhileStmt{
+			Token: token.Token{Type: token.WHILE, Lexeme: "while"},
+		},
+	},
+}
+
+func TestMissingNodesString(t *testing.T) {
+	expectedStr := `print (1 + );while  `
+	if missingNodesProg.String() != expectedStr {
+		t.Fatalf("Program with missing nodes String mismatch. Expected: %q, got=%q",
+			expectedStr, missingNodesProg)
+	}
+}
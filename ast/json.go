@@ -0,0 +1,360 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"golox/token"
+)
+
+// EncodeJSON encodes a node and all of its children as JSON.
+// Every node object has a "kind" field naming its Go type,
+// and every token keeps its position in the source.
+func EncodeJSON(node Node) ([]byte, error) {
+	return json.Marshal(encodeNode(node))
+}
+
+// DecodeJSON decodes JSON produced by EncodeJSON back into the same node types
+func DecodeJSON(data []byte) (node Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			node, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return decodeNode(raw), nil
+}
+
+type jsonToken struct {
+	Type       string      `json:"type"`
+	Lexeme     string      `json:"lexeme"`
+	Line       int         `json:"line"`
+	LineOffset int         `json:"lineOffset"`
+	Literal    interface{} `json:"literal"`
+}
+
+type jsonObj map[string]interface{}
+
+func encodeToken(tok token.Token) jsonToken {
+	return jsonToken{tok.Type.String(), tok.Lexeme, tok.Line, tok.LineOffset, tok.Literal}
+}
+
+func encodeList(nodes []Node) []interface{} {
+	list := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		list[i] = encodeNode(n)
+	}
+	return list
+}
+
+func encodeNode(node Node) interface{} {
+	switch node := node.(type) {
+	case nil:
+		return nil
+	case *Program:
+		stmts := make([]Node, len(node.Statements))
+		for i, s := range node.Statements {
+			stmts[i] = s
+		}
+		return jsonObj{"kind": "Program", "statements": encodeList(stmts)}
+	case *ExprStmt:
+		return jsonObj{"kind": "ExprStmt", "token": encodeToken(node.Token), "expr": encodeNode(node.Expr)}
+	case *PrintStmt:
+		return jsonObj{"kind": "PrintStmt", "token": encodeToken(node.Token), "expr": encodeNode(node.Expr)}
+	case *AssignStmt:
+		return jsonObj{"kind": "AssignStmt", "name": encodeNode(node.Name), "expr": encodeNode(node.Expr)}
+	case *SetStmt:
+		return jsonObj{"kind": "SetStmt", "token": encodeToken(node.Token), "object": encodeNode(node.Object),
+			"name": encodeNode(node.Name), "value": encodeNode(node.Value)}
+	case *FuncDeclStmt:
+		params := make([]Node, len(node.Params))
+		for i, p := range node.Params {
+			params[i] = p
+		}
+		obj := jsonObj{"kind": "FuncDeclStmt", "token": encodeToken(node.Token), "name": encodeNode(node.Name),
+			"params": encodeList(params), "body": encodeNode(node.Body)}
+		// type annotations are only encoded when present
+		if node.ParamTypes != nil {
+			types := make([]Node, len(node.ParamTypes))
+			for i, t := range node.ParamTypes {
+				types[i] = t
+			}
+			obj["paramTypes"] = encodeList(types)
+		}
+		if node.ReturnType != nil {
+			obj["returnType"] = encodeNode(node.ReturnType)
+		}
+		return obj
+	case *VarStmt:
+		obj := jsonObj{"kind": "VarStmt", "token": encodeToken(node.Token), "name": encodeNode(node.Name),
+			"value": encodeNode(node.Value)}
+		if node.Type != nil {
+			obj["type"] = encodeNode(node.Type)
+		}
+		return obj
+	case *BlockStmt:
+		if node == nil {
+			return nil
+		}
+		stmts := make([]Node, len(node.Statements))
+		for i, s := range node.Statements {
+			stmts[i] = s
+		}
+		return jsonObj{"kind": "BlockStmt", "token": encodeToken(node.Token), "statements": encodeList(stmts),
+			"endToken": encodeToken(node.EndToken)}
+	case *IfStmt:
+		return jsonObj{"kind": "IfStmt", "token": encodeToken(node.Token), "cond": encodeNode(node.Cond),
+			"onTrue": encodeNode(node.OnTrue), "onFalse": encodeNode(node.OnFalse)}
+	case *WhileStmt:
+		return jsonObj{"kind": "WhileStmt", "token": encodeToken(node.Token), "cond": encodeNode(node.Cond),
+			"body": encodeNode(node.Body)}
+	case *ReturnStmt:
+		return jsonObj{"kind": "ReturnStmt", "token": encodeToken(node.Token), "returnValue": encodeNode(node.ReturnValue)}
+	case *DebuggerStmt:
+		return jsonObj{"kind": "DebuggerStmt", "token": encodeToken(node.Token)}
+	case *ImportStmt:
+		return jsonObj{"kind": "ImportStmt", "token": encodeToken(node.Token), "path": encodeToken(node.Path),
+			"alias": encodeNode(node.Alias)}
+	// literals and identifiers are values in parsed programs,
+	// so pointers to them are encoded the same way
+	case *Identifier:
+		if node == nil {
+			return nil
+		}
+		return encodeNode(*node)
+	case *NumExpr:
+		return encodeNode(*node)
+	case *NilExpr:
+		return encodeNode(*node)
+	case *StrExpr:
+		return encodeNode(*node)
+	case *BoolExpr:
+		return encodeNode(*node)
+	case Identifier:
+		return jsonObj{"kind": "Identifier", "token": encodeToken(node.Token)}
+	case NumExpr:
+		return jsonObj{"kind": "NumExpr", "token": encodeToken(node.Token)}
+	case NilExpr:
+		return jsonObj{"kind": "NilExpr", "token": encodeToken(node.Token)}
+	case StrExpr:
+		return jsonObj{"kind": "StrExpr", "token": encodeToken(node.Token)}
+	case BoolExpr:
+		return jsonObj{"kind": "BoolExpr", "token": encodeToken(node.Token)}
+	case *PrefixExpr:
+		return jsonObj{"kind": "PrefixExpr", "token": encodeToken(node.Token), "right": encodeNode(node.Right)}
+	case *InfixExpr:
+		return jsonObj{"kind": "InfixExpr", "left": encodeNode(node.Left), "token": encodeToken(node.Token),
+			"right": encodeNode(node.Right)}
+	case *CallExpr:
+		args := make([]Node, len(node.Args))
+		for i, a := range node.Args {
+			args[i] = a
+		}
+		return jsonObj{"kind": "CallExpr", "token": encodeToken(node.Token), "function": encodeNode(node.Function),
+			"args": encodeList(args)}
+	case *GetExpr:
+		return jsonObj{"kind": "GetExpr", "token": encodeToken(node.Token), "object": encodeNode(node.Object),
+			"name": encodeNode(node.Name)}
+	case *MethodCallExpr:
+		args := make([]Node, len(node.Args))
+		for i, a := range node.Args {
+			args[i] = a
+		}
+		return jsonObj{"kind": "MethodCallExpr", "token": encodeToken(node.Token), "object": encodeNode(node.Object),
+			"name": encodeNode(node.Name), "args": encodeList(args)}
+	}
+	panic(fmt.Sprintf("Unable to encode unexpected node, got: %T", node))
+}
+
+// Token types by name, for decoding
+var tokenTypes = func() map[string]token.TokenType {
+	types := make(map[string]token.TokenType)
+	for t := token.LEFT_PAREN; t <= token.INVALID; t++ {
+		types[t.String()] = t
+	}
+	return types
+}()
+
+func decodeToken(raw json.RawMessage) token.Token {
+	var jt jsonToken
+	if err := json.Unmarshal(raw, &jt); err != nil {
+		panic(fmt.Sprintf("Invalid token: %s", err))
+	}
+	t, found := tokenTypes[jt.Type]
+	if !found {
+		panic(fmt.Sprintf("Unknown token type %q", jt.Type))
+	}
+	return token.NewToken(t, jt.Lexeme, jt.Line, jt.LineOffset, jt.Literal)
+}
+
+func decodeFields(raw json.RawMessage) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		panic(fmt.Sprintf("Invalid node: %s", err))
+	}
+	return fields
+}
+
+func decodeList(raw json.RawMessage) []Node {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		panic(fmt.Sprintf("Invalid node list: %s", err))
+	}
+	nodes := make([]Node, len(list))
+	for i, item := range list {
+		nodes[i] = decodeNode(item)
+	}
+	return nodes
+}
+
+func decodeStmts(raw json.RawMessage) []Stmt {
+	nodes := decodeList(raw)
+	stmts := make([]Stmt, len(nodes))
+	for i, n := range nodes {
+		stmts[i] = asStmt(n)
+	}
+	return stmts
+}
+
+func decodeExpr(raw json.RawMessage) Expr {
+	n := decodeNode(raw)
+	if n == nil {
+		return nil
+	}
+	expr, ok := n.(Expr)
+	if !ok {
+		panic(fmt.Sprintf("Expected expression, got: %T", n))
+	}
+	return expr
+}
+
+func decodeArgs(raw json.RawMessage) []Expr {
+	var args []Expr
+	for _, a := range decodeList(raw) {
+		expr, ok := a.(Expr)
+		if !ok {
+			panic(fmt.Sprintf("Expected expression, got: %T", a))
+		}
+		args = append(args, expr)
+	}
+	return args
+}
+
+func decodeIdent(raw json.RawMessage) *Identifier {
+	n := decodeNode(raw)
+	if n == nil {
+		return nil
+	}
+	ident, ok := n.(Identifier)
+	if !ok {
+		panic(fmt.Sprintf("Expected identifier, got: %T", n))
+	}
+	return &ident
+}
+
+func decodeBlock(raw json.RawMessage) *BlockStmt {
+	n := decodeNode(raw)
+	if n == nil {
+		return nil
+	}
+	block, ok := n.(*BlockStmt)
+	if !ok {
+		panic(fmt.Sprintf("Expected block statement, got: %T", n))
+	}
+	return block
+}
+
+func asStmt(n Node) Stmt {
+	stmt, ok := n.(Stmt)
+	if !ok {
+		panic(fmt.Sprintf("Expected statement, got: %T", n))
+	}
+	return stmt
+}
+
+func decodeNode(raw json.RawMessage) Node {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	f := decodeFields(raw)
+	var kind string
+	if err := json.Unmarshal(f["kind"], &kind); err != nil {
+		panic("Node is missing its kind")
+	}
+	switch kind {
+	case "Program":
+		return &Program{Statements: decodeStmts(f["statements"])}
+	case "ExprStmt":
+		return &ExprStmt{Token: decodeToken(f["token"]), Expr: decodeExpr(f["expr"])}
+	case "PrintStmt":
+		return &PrintStmt{Token: decodeToken(f["token"]), Expr: decodeExpr(f["expr"])}
+	case "AssignStmt":
+		return &AssignStmt{Name: *decodeIdent(f["name"]), Expr: decodeExpr(f["expr"])}
+	case "FuncDeclStmt":
+		var params []*Identifier
+		for _, p := range decodeList(f["params"]) {
+			ident, ok := p.(Identifier)
+			if !ok {
+				panic(fmt.Sprintf("Expected identifier, got: %T", p))
+			}
+			params = append(params, &ident)
+		}
+		var paramTypes []*Identifier
+		if raw, found := f["paramTypes"]; found {
+			paramTypes = []*Identifier{}
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				panic(fmt.Sprintf("Invalid node list: %s", err))
+			}
+			for _, item := range list {
+				paramTypes = append(paramTypes, decodeIdent(item))
+			}
+		}
+		return &FuncDeclStmt{Token: decodeToken(f["token"]), Name: decodeIdent(f["name"]),
+			Params: params, ParamTypes: paramTypes, ReturnType: decodeIdent(f["returnType"]), Body: decodeBlock(f["body"])}
+	case "VarStmt":
+		return &VarStmt{Token: decodeToken(f["token"]), Name: decodeIdent(f["name"]), Type: decodeIdent(f["type"]),
+			Value: decodeExpr(f["value"])}
+	case "BlockStmt":
+		return &BlockStmt{Token: decodeToken(f["token"]), Statements: decodeStmts(f["statements"]),
+			EndToken: decodeToken(f["endToken"])}
+	case "IfStmt":
+		return &IfStmt{Token: decodeToken(f["token"]), Cond: decodeExpr(f["cond"]),
+			OnTrue: decodeBlock(f["onTrue"]), OnFalse: decodeBlock(f["onFalse"])}
+	case "WhileStmt":
+		return &WhileStmt{Token: decodeToken(f["token"]), Cond: decodeExpr(f["cond"]), Body: decodeBlock(f["body"])}
+	case "ReturnStmt":
+		return &ReturnStmt{Token: decodeToken(f["token"]), ReturnValue: decodeExpr(f["returnValue"])}
+	case "DebuggerStmt":
+		return &DebuggerStmt{Token: decodeToken(f["token"])}
+	case "ImportStmt":
+		return &ImportStmt{Token: decodeToken(f["token"]), Path: decodeToken(f["path"]), Alias: decodeIdent(f["alias"])}
+	case "Identifier":
+		return Identifier{Token: decodeToken(f["token"])}
+	case "NumExpr":
+		return NumExpr{Token: decodeToken(f["token"])}
+	case "NilExpr":
+		return NilExpr{Token: decodeToken(f["token"])}
+	case "StrExpr":
+		return StrExpr{Token: decodeToken(f["token"])}
+	case "BoolExpr":
+		return BoolExpr{Token: decodeToken(f["token"])}
+	case "PrefixExpr":
+		return &PrefixExpr{Token: decodeToken(f["token"]), Right: decodeExpr(f["right"])}
+	case "InfixExpr":
+		return &InfixExpr{Left: decodeExpr(f["left"]), Token: decodeToken(f["token"]), Right: decodeExpr(f["right"])}
+	case "SetStmt":
+		return &SetStmt{Token: decodeToken(f["token"]), Object: decodeExpr(f["object"]), Name: decodeIdent(f["name"]),
+			Value: decodeExpr(f["value"])}
+	case "CallExpr":
+		return &CallExpr{Token: decodeToken(f["token"]), Function: decodeIdent(f["function"]), Args: decodeArgs(f["args"])}
+	case "GetExpr":
+		return &GetExpr{Token: decodeToken(f["token"]), Object: decodeExpr(f["object"]), Name: decodeIdent(f["name"])}
+	case "MethodCallExpr":
+		return &MethodCallExpr{Token: decodeToken(f["token"]), Object: decodeExpr(f["object"]), Name: decodeIdent(f["name"]),
+			Args: decodeArgs(f["args"])}
+	}
+	panic(fmt.Sprintf("Unknown node kind %q", kind))
+}
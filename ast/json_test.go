@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+package ast_test
+
+import (
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRoundTrip(t *testing.T, source string) {
+	l := lexer.NewLexer(source)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Unexpected parser errors for %q: %s", source, p.Errors())
+	}
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		t.Fatalf("Unexpected error encoding %q: %s", source, err)
+	}
+	decoded, err := ast.DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("Unexpected error decoding %s: %s", data, err)
+	}
+	if !reflect.DeepEqual(decoded, program) {
+		t.Fatalf("Decoded program differs. expected=%q, got=%q", program, decoded)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	progs := []string{
+		`var x = 1.5; x = x * -2; print "str";`,
+		`fun f(a, b) { if a < b { return a; } else { return; } } f(1, nil);`,
+		`while !(true and false) { {} }`,
+		`var x: Num = 1; fun f(a: Str, b): Bool { return true; } fun g(): Nil {}`,
+		`print a.b; a.b.c = f(a).m(1, x.y);`,
+		`import "lib/math.lox"; import "util.lox" as u; print math.pi;`,
+	}
+	for _, prog := range progs {
+		testRoundTrip(t, prog)
+	}
+}
+
+func TestJSONRoundTripExamples(t *testing.T) {
+	paths, _ := filepath.Glob("../examples/*.lox")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testRoundTrip(t, string(src))
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	l := lexer.NewLexer("print x;")
+	p := parser.New(&l)
+	data, _ := ast.EncodeJSON(p.ParseProgram())
+	for _, field := range []string{`"kind":"PrintStmt"`, `"kind":"Identifier"`, `"type":"IDENTIFIER"`, `"lineOffset":6`} {
+		if !strings.Contains(string(data), field) {
+			t.Fatalf("Expected %s in encoded program, got: %s", field, data)
+		}
+	}
+}
+
+func TestJSONDecodeInvalid(t *testing.T) {
+	inputs := []string{
+		`{"kind":"Unknown"}`,
+		`{"kind":"VarStmt","token":{"type":"NOPE"}}`,
+		`{"kind":"Program","statements":[{"kind":"NumExpr","token":{"type":"NUMBER"}}]}`,
+		`[`,
+	}
+	for _, input := range inputs {
+		if _, err := ast.DecodeJSON([]byte(input)); err == nil {
+			t.Fatalf("Expected error decoding %s", input)
+		}
+	}
+}
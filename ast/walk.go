@@ -0,0 +1,121 @@
+package ast
+
+// Inspect traverses the tree rooted at node in depth-first order,
+// calling f for each node. If f returns false, the children of
+// that node are skipped. Nil children are not visited.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			Inspect(s, f)
+		}
+	case *ExprStmt:
+		Inspect(node.Expr, f)
+	case *PrintStmt:
+		Inspect(node.Expr, f)
+	case *AssignStmt:
+		Inspect(node.Name, f)
+		Inspect(node.Expr, f)
+	case *SetStmt:
+		Inspect(node.Object, f)
+		Inspect(node.Name, f)
+		Inspect(node.Value, f)
+	case *FuncDeclStmt:
+		Inspect(node.Name, f)
+		for i, p := range node.Params {
+			Inspect(p, f)
+			Inspect(node.ParamType(i), f)
+		}
+		Inspect(node.ReturnType, f)
+		Inspect(node.Body, f)
+	case *VarStmt:
+		Inspect(node.Name, f)
+		Inspect(node.Type, f)
+		Inspect(node.Value, f)
+	case *BlockStmt:
+		for _, s := range node.Statements {
+			Inspect(s, f)
+		}
+	case *IfStmt:
+		Inspect(node.Cond, f)
+		Inspect(node.OnTrue, f)
+		Inspect(node.OnFalse, f)
+	case *WhileStmt:
+		Inspect(node.Cond, f)
+		Inspect(node.Body, f)
+	case *ReturnStmt:
+		Inspect(node.ReturnValue, f)
+	case *ImportStmt:
+		Inspect(node.Alias, f)
+	case *PrefixExpr:
+		Inspect(node.Right, f)
+	case *InfixExpr:
+		Inspect(node.Left, f)
+		Inspect(node.Right, f)
+	case *CallExpr:
+		Inspect(node.Function, f)
+		for _, a := range node.Args {
+			Inspect(a, f)
+		}
+	case *GetExpr:
+		Inspect(node.Object, f)
+		Inspect(node.Name, f)
+	case *MethodCallExpr:
+		Inspect(node.Object, f)
+		Inspect(node.Name, f)
+		for _, a := range node.Args {
+			Inspect(a, f)
+		}
+	}
+}
+
+// Returns if node is nil, or a nil pointer to a node,
+// as left behind by the parser for statements with errors
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *Program:
+		return node == nil
+	case *ExprStmt:
+		return node == nil
+	case *PrintStmt:
+		return node == nil
+	case *AssignStmt:
+		return node == nil
+	case *SetStmt:
+		return node == nil
+	case *FuncDeclStmt:
+		return node == nil
+	case *VarStmt:
+		return node == nil
+	case *BlockStmt:
+		return node == nil
+	case *IfStmt:
+		return node == nil
+	case *WhileStmt:
+		return node == nil
+	case *ReturnStmt:
+		return node == nil
+	case *DebuggerStmt:
+		return node == nil
+	case *ImportStmt:
+		return node == nil
+	case *Identifier:
+		return node == nil
+	case *PrefixExpr:
+		return node == nil
+	case *InfixExpr:
+		return node == nil
+	case *CallExpr:
+		return node == nil
+	case *GetExpr:
+		return node == nil
+	case *MethodCallExpr:
+		return node == nil
+	}
+	return false
+}
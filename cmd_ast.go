@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golox/ast"
+	"os"
+)
+
+const astUsage = "golox ast [--json] file"
+
+// RunAst prints the syntax tree of a Lox file
+func RunAst(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+astUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	prog, status := parseFile(flags.Arg(0))
+	if prog == nil {
+		return status
+	}
+	if !*asJSON {
+		fmt.Println(prog)
+		return 0
+	}
+	data, err := ast.EncodeJSON(prog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 70
+	}
+	var indented json.RawMessage = data
+	out, _ := json.MarshalIndent(indented, "", "  ")
+	fmt.Println(string(out))
+	return 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/lexer"
+	"golox/parser"
+	"golox/types"
+	"os"
+)
+
+const checkUsage = "golox check [--types] file ..."
+
+// RunCheck reports the errors in Lox files without running them
+func RunCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	checkTypes := flags.Bool("types", false, "also infer and check types, and the type annotations")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+checkUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 64
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 64
+			continue
+		}
+		l := lexer.NewLexer(string(src))
+		p := parser.New(&l)
+		prog := p.ParseProgram()
+		for _, e := range l.Errors() {
+			fmt.Printf("%s:%d:%d: %s\n", path, e.Token.Line+1, e.Token.LineOffset+1, e.Msg)
+		}
+		for _, e := range p.Errors() {
+			fmt.Printf("%s:%d:%d: %s\n", path, e.Token.Line+1, e.Token.LineOffset+1, e)
+		}
+		if len(l.Errors()) > 0 || len(p.Errors()) > 0 {
+			status = 65
+			continue
+		}
+		if !*checkTypes {
+			continue
+		}
+		for _, e := range types.Check(prog) {
+			fmt.Printf("%s:%d:%d: %s\n", path, e.Token.Line+1, e.Token.LineOffset+1, e.Msg)
+			status = 65
+		}
+	}
+	return status
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/dap"
+	"os"
+)
+
+const dapUsage = "golox dap " + permissionUsage
+
+// RunDap serves the Debug Adapter Protocol over stdio
+func RunDap(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	perms := permissionFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+dapUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 64
+	}
+	s := dap.NewServer(os.Stdin, os.Stdout)
+	s.SetPermissions(perms)
+	s.SetImportPath(importPath())
+	if err := s.Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
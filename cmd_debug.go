@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"golox/debug"
+	"golox/interp"
+	"golox/repl"
+	"golox/stdlib"
+	"os"
+	"strconv"
+)
+
+// Lines given by repeated flags
+type lineList []int
+
+func (l *lineList) String() string { return fmt.Sprint(*l) }
+func (l *lineList) Set(s string) error {
+	line, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid line number %q", s)
+	}
+	*l = append(*l, line)
+	return nil
+}
+
+const debugUsage = "golox debug [--entry] [-b line ...] " + permissionUsage + " file"
+
+// RunDebug runs a Lox file under the terminal debugger
+func RunDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	entry := flags.Bool("entry", false, "stop before the first statement")
+	var breaks lineList
+	flags.Var(&breaks, "b", "set a breakpoint on a `line` (repeatable)")
+	perms := permissionFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+debugUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	prog, status := parseFile(flags.Arg(0))
+	if prog == nil {
+		return status
+	}
+	src, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+
+	reader := repl.NewReader(os.Stdin, os.Stdout, nil)
+	console := debug.NewConsole(prog, string(src), reader.ReadLine, os.Stdout)
+	for _, line := range breaks {
+		if err := console.Break(line); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+	}
+	if *entry {
+		console.Debugger().StopOnEntry()
+	}
+	intp := interp.New()
+	stdlib.Install(&intp, perms)
+	stdlib.LimitImports(&intp, perms)
+	intp.File, intp.ImportPath = flags.Arg(0), importPath()
+	switch err := console.Run(&intp, prog); err {
+	case nil, debug.ErrAborted:
+		return 0
+	default:
+		fmt.Fprintln(os.Stderr, color.RedString("Runtime Error:"), err)
+		return 70
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/format"
+	"io"
+	"os"
+)
+
+const fmtUsage = "golox fmt [--check] [-w] [file ...]"
+
+// RunFmt formats Lox source files, or stdin if no files are given
+func RunFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "exit with status 1 if any file is not formatted")
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+fmtUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+		out, err := format.Source(string(src))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 65
+		}
+		if *check {
+			if out != string(src) {
+				return 1
+			}
+			return 0
+		}
+		fmt.Print(out)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 64
+			continue
+		}
+		out, err := format.Source(string(src))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+			status = 65
+			continue
+		}
+		switch {
+		case *check:
+			if out != string(src) {
+				fmt.Println(path)
+				if status == 0 {
+					status = 1
+				}
+			}
+		case *write:
+			if out != string(src) {
+				if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					status = 64
+				}
+			}
+		default:
+			fmt.Print(out)
+		}
+	}
+	return status
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"golox/lint"
+	"golox/loxtest"
+	"golox/stdlib"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const lintUsage = "golox lint [--config file] [--disable rule,...] [--rules] [dir|file ...]"
+
+// RunLint reports likely mistakes in Lox files, or in every .lox file under directories
+func RunLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	configPath := flags.String("config", "", "read the config from this JSON file instead of "+lint.ConfigFile)
+	disable := flags.String("disable", "", "comma-separated IDs of rules not to run")
+	list := flags.Bool("rules", false, "list every rule and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+lintUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-22s %s\n", rule.ID, rule.Doc)
+		}
+		return 0
+	}
+
+	config := &lint.Config{}
+	if *configPath != "" {
+		c, err := lint.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+		config = c
+	} else if c, err := lint.LoadConfig(lint.ConfigFile); err == nil {
+		config = c
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+	if *disable != "" {
+		config.Disable = append(config.Disable, strings.Split(*disable, ",")...)
+		if err := config.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := loxFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+
+	// programs run with the builtins of stdlib declared
+	config.Globals = append(config.Globals, stdlib.Names()...)
+	// tests can call the assertion builtins of "golox test"
+	testConfig := *config
+	for _, b := range loxtest.Builtins() {
+		testConfig.Globals = append(testConfig.Globals, b.Name)
+	}
+
+	status := 0
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 64
+			continue
+		}
+		c := config
+		if strings.HasSuffix(path, "_test.lox") {
+			c = &testConfig
+		}
+		warnings, parseErrors := lint.Source(string(src), c)
+		for _, e := range parseErrors {
+			fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", path, e.Token.Line+1, e.Token.LineOffset+1, e)
+			status = 65
+		}
+		for _, w := range warnings {
+			fmt.Printf("%s:%s\n", path, w)
+			if status == 0 {
+				status = 1
+			}
+		}
+	}
+	return status
+}
+
+// Returns the paths that are files, along with every .lox file under the directories
+func loxFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if file == path && !info.IsDir() || !info.IsDir() && filepath.Ext(file) == ".lox" {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"golox/lsp"
+	"os"
+)
+
+const lspUsage = "golox lsp"
+
+// RunLsp serves the Language Server Protocol over stdio
+func RunLsp(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: "+lspUsage)
+		return 64
+	}
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
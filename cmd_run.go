@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"golox/ast"
+	"golox/coverage"
+	"golox/interp"
+	"golox/profile"
+	"golox/stdlib"
+	"golox/trace"
+	"io"
+	"os"
+)
+
+const runUsage = "golox run [--trace] [--profile file] [--collapsed file] [--coverage file] [--timeout duration] [--max-steps n] " + permissionUsage + " file"
+
+// RunRun interprets a Lox file without showing the parsed program
+func RunRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	traced := flags.Bool("trace", false, "print a trace of the execution to stderr")
+	pprofPath := flags.String("profile", "", "write a pprof profile of time per function and line to `file`")
+	collapsedPath := flags.String("collapsed", "", "write the profile as collapsed stacks for flamegraph tools to `file`")
+	coverPath := flags.String("coverage", "", "write an lcov report of the statements executed to `file` and print a summary")
+	timeout := flags.Duration("timeout", 0, "stop the program after `duration`, such as 5s")
+	maxSteps := flags.Int("max-steps", 0, "stop the program after evaluating `n` nodes")
+	perms := permissionFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+runUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	prog, status := parseFile(flags.Arg(0))
+	if prog == nil {
+		return status
+	}
+	intp := interp.New()
+	stdlib.Install(&intp, perms)
+	stdlib.LimitImports(&intp, perms)
+	intp.File, intp.ImportPath = flags.Arg(0), importPath()
+	intp.MaxSteps = *maxSteps
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	var tracers []interp.Tracer
+	if *traced {
+		tracers = append(tracers, trace.NewPrinter(os.Stderr))
+	}
+	var profiler *profile.Profiler
+	if *pprofPath != "" || *collapsedPath != "" {
+		profiler = profile.New(flags.Arg(0))
+		tracers = append(tracers, profiler)
+	}
+	var cover *coverage.Coverage
+	if *coverPath != "" {
+		cover = coverage.New()
+		cover.Add(flags.Arg(0), prog)
+		tracers = append(tracers, cover)
+	}
+	switch len(tracers) {
+	case 0:
+	case 1:
+		intp.Tracer = tracers[0]
+	default:
+		intp.Tracer = interp.MultiTracer(tracers...)
+	}
+
+	status = runProgram(ctx, &intp, prog)
+	if profiler != nil {
+		// a profile of a failed run still shows where the time went
+		if err := writeFile(*pprofPath, profiler.WritePprof); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+		if err := writeFile(*collapsedPath, profiler.WriteCollapsed); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+	}
+	if cover != nil {
+		if err := writeFile(*coverPath, cover.WriteLcov); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+		cover.WriteSummary(os.Stderr)
+	}
+	return status
+}
+
+// Evaluates prog, printing any runtime error.
+// Returns the exit status.
+func runProgram(ctx context.Context, intp *interp.Interpreter, prog *ast.Program) (status int) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintln(intp.Stderr, color.RedString("Runtime Error:"), err)
+			status = 70
+		}
+	}()
+	intp.EvalContext(ctx, prog)
+	return 0
+}
+
+// Creates the file at path with the output of write, unless path is empty
+func writeFile(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+//go:build integration
+// +build integration
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs golox run with args, returning its exit status and what it wrote to stderr
+func runRun(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	status := RunRun(args)
+	w.Close()
+	return status, <-out
+}
+
+func TestRunImportPermission(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	scripts := filepath.Join(dir, "scripts")
+	for _, d := range []string{secrets, scripts} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(secrets, "env"), []byte(`KEY="sk_live_abc123"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(scripts, "lib.lox"), []byte("var x = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	leak := filepath.Join(scripts, "leak.lox")
+	src := `import "` + filepath.ToSlash(filepath.Join(secrets, "env")) + `" as e;`
+	if err := os.WriteFile(leak, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(scripts, "lib_user.lox")
+	if err := os.WriteFile(lib, []byte("import \"lib.lox\";\nprint lib.x;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		stderr string
+	}{
+		{[]string{leak}, 70, "Importing modules is not allowed."},
+		{[]string{"--allow-read=" + scripts, leak}, 70, "Cannot find module"},
+		{[]string{lib}, 70, "Importing modules is not allowed."},
+		{[]string{"--allow-read=" + scripts, lib}, 0, ""},
+	}
+	for _, test := range tests {
+		status, stderr := runRun(t, test.args...)
+		if status != test.status {
+			t.Errorf("golox run %s exited with %d, expected %d: %s", strings.Join(test.args, " "), status, test.status, stderr)
+		}
+		if !strings.Contains(stderr, test.stderr) {
+			t.Errorf("golox run %s printed %q, expected it to contain %q", strings.Join(test.args, " "), stderr, test.stderr)
+		}
+		if strings.Contains(stderr, "sk_live_abc123") {
+			t.Errorf("golox run %s printed the contents of a file it cannot read: %s", strings.Join(test.args, " "), stderr)
+		}
+	}
+}
+
+func TestRunImportOutsideGrant(t *testing.T) {
+	dir := t.TempDir()
+	scripts := filepath.Join(dir, "scripts")
+	if err := os.Mkdir(scripts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "exists.lox"), []byte("var x = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the errors for a file outside the grant must not tell whether it exists
+	var errs []string
+	for _, name := range []string{"exists.lox", "missing.lox"} {
+		path := filepath.ToSlash(filepath.Join(dir, name))
+		script := filepath.Join(scripts, "main.lox")
+		if err := os.WriteFile(script, []byte(`import "`+path+`" as m;`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		status, stderr := runRun(t, "--allow-read="+scripts, script)
+		if status != 70 {
+			t.Fatalf("Expected importing %s to fail, exited with %d: %s", name, status, stderr)
+		}
+		errs = append(errs, strings.ReplaceAll(stderr, name, "NAME"))
+	}
+	if errs[0] != errs[1] {
+		t.Fatalf("Expected the same error for existing and missing files, got:\n%s\n%s", errs[0], errs[1])
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golox/coverage"
+	"golox/loxtest"
+	"io"
+	"os"
+	"regexp"
+)
+
+const testUsage = "golox test [-run regexp] [-v] [--tap] [--junit file] [--coverage file] [--timeout duration] [--max-steps n] " + permissionUsage + " [dir|file ...]"
+
+// RunTest runs the tests in *_test.lox files
+func RunTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	run := flags.String("run", "", "only run tests with names matching `regexp`")
+	verbose := flags.Bool("v", false, "list every test, not only failures")
+	tap := flags.Bool("tap", false, "print the results in the Test Anything Protocol")
+	junitPath := flags.String("junit", "", "write the results as JUnit XML to `file`")
+	coverPath := flags.String("coverage", "", "write an lcov report of the statements executed to `file` and print a summary")
+	timeout := flags.Duration("timeout", 0, "fail each test running longer than `duration`, such as 5s")
+	maxSteps := flags.Int("max-steps", 0, "fail each test evaluating more than `n` nodes")
+	perms := permissionFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+testUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	opts := loxtest.Options{Timeout: *timeout, MaxSteps: *maxSteps, Permissions: perms, ImportPath: importPath()}
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -run pattern: %s\n", err)
+			return 64
+		}
+		opts.Run = re
+	}
+	var cover *coverage.Coverage
+	if *coverPath != "" {
+		cover = coverage.New()
+		opts.Tracer = cover
+	}
+
+	files, err := loxtest.Files(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+	var results []*loxtest.Result
+	for _, file := range files {
+		prog, status := parseFile(file)
+		if prog == nil {
+			return status
+		}
+		if cover != nil {
+			cover.Add(file, prog)
+		}
+		results = append(results, loxtest.RunFile(file, prog, opts)...)
+	}
+
+	if *tap {
+		loxtest.WriteTAP(os.Stdout, results)
+	} else {
+		loxtest.WriteText(os.Stdout, results, *verbose)
+	}
+	if err := writeFile(*junitPath, func(w io.Writer) error { return loxtest.WriteJUnit(w, results) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+	if cover != nil {
+		if err := writeFile(*coverPath, cover.WriteLcov); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 64
+		}
+		cover.WriteSummary(os.Stderr)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golox/lexer"
+	"golox/token"
+	"os"
+	"text/tabwriter"
+)
+
+// Token as printed by "golox tokens --json"
+type jsonToken struct {
+	Type       string      `json:"type"`
+	Lexeme     string      `json:"lexeme"`
+	Literal    interface{} `json:"literal"`
+	Line       int         `json:"line"`
+	LineOffset int         `json:"lineOffset"`
+	Error      string      `json:"error,omitempty"`
+}
+
+const tokensUsage = "golox tokens [--json] [--comments] [--errors] file"
+
+// RunTokens prints the tokens the lexer produces for a Lox file
+func RunTokens(args []string) int {
+	flags := flag.NewFlagSet("tokens", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per token")
+	comments := flags.Bool("comments", false, "include COMMENT tokens")
+	errors := flags.Bool("errors", false, "include INVALID tokens along with their error")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: "+tokensUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	bytes, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 64
+	}
+	l := lexer.NewLexer(string(bytes))
+	l.KeepComments = *comments
+	toks := l.ScanTokens()
+
+	// every INVALID token has exactly one error, in the same order
+	lexErrors := l.Errors()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, tok := range toks {
+		msg := ""
+		if tok.Type == token.INVALID {
+			msg, lexErrors = lexErrors[0].Msg, lexErrors[1:]
+			if !*errors {
+				continue
+			}
+		}
+		if *asJSON {
+			enc.Encode(jsonToken{tok.Type.String(), tok.Lexeme, tok.Literal, tok.Line, tok.LineOffset, msg})
+			continue
+		}
+		literal := "nil"
+		if s, isStr := tok.Literal.(string); isStr {
+			// quoted like the lexeme, so that strings spanning lines keep the token on one
+			literal = fmt.Sprintf("%q", s)
+		} else if tok.Literal != nil {
+			literal = fmt.Sprint(tok.Literal)
+		}
+		fmt.Fprintf(w, "%d:%d\t%s\t%q\t%s", tok.Line, tok.LineOffset, tok.Type, tok.Lexeme, literal)
+		if msg != "" {
+			fmt.Fprintf(w, "\t%s", msg)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	if len(l.Errors()) > 0 {
+		return 65
+	}
+	return 0
+}
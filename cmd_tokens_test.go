@@ -0,0 +1,55 @@
+//go:build integration
+// +build integration
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs golox tokens with args, returning its exit status and what it wrote to stdout
+func runTokens(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	status := RunTokens(args)
+	w.Close()
+	return status, <-out
+}
+
+func TestTokensMultilineString(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.lox")
+	if err := os.WriteFile(path, []byte("var s = \"a\nb\";\nprint s;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out := runTokens(t, path)
+	if status != 0 {
+		t.Fatalf("golox tokens exited with %d: %s", status, out)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	// var s = "a\nb" ; print s ; EOF
+	if len(lines) != 9 {
+		t.Fatalf("Expected one line per token, got %d lines:\n%s", len(lines), out)
+	}
+	if fields := strings.Fields(lines[3]); len(fields) != 4 || fields[1] != "STRING" || fields[2] != `"\"a\nb\""` || fields[3] != `"a\nb"` {
+		t.Fatalf("Expected the string token with its quoted lexeme and literal, got %q", lines[3])
+	}
+	// the columns line up across the string
+	if strings.Index(lines[0], "VAR") != strings.Index(lines[5], "PRINT") {
+		t.Fatalf("Expected aligned columns, got:\n%s", out)
+	}
+}
@@ -0,0 +1,242 @@
+// Package coverage records which statements of Lox programs are executed
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"golox/ast"
+	"golox/obj"
+	"golox/token"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Coverage is an interp.Tracer counting how many times each statement of
+// the programs added to it is executed, and how many times each function is called
+type Coverage struct {
+	files    []*File
+	stmts    map[ast.Stmt]*stmt
+	declared map[string]*Func // last executed declaration of each function, by name
+	calling  bool             // a function was called and its first statement not reached yet
+}
+
+// File is the coverage of a program
+type File struct {
+	Path  string
+	Funcs []*Func // in declaration order
+	stmts []*stmt
+}
+
+// Func is the coverage of a function declaration
+type Func struct {
+	Name  string
+	Line  int // counted from 0
+	Calls int
+	stmts []*stmt
+}
+
+// A statement that can be executed
+type stmt struct {
+	line int
+	hits int
+	fn   *Func // enclosing function, nil at the top level
+	decl *Func // function declared by the statement, if any
+}
+
+// New returns an empty Coverage
+func New() *Coverage {
+	return &Coverage{stmts: make(map[ast.Stmt]*stmt), declared: make(map[string]*Func)}
+}
+
+// Add registers the statements of prog, parsed from the file at path,
+// so those never executed are reported
+func (c *Coverage) Add(path string, prog *ast.Program) {
+	f := &File{Path: path}
+	c.files = append(c.files, f)
+	c.addStmts(f, nil, prog.Statements)
+}
+
+func (c *Coverage) addStmts(f *File, fn *Func, stmts []ast.Stmt) {
+	for _, s := range stmts {
+		c.addStmt(f, fn, s)
+	}
+}
+
+// Registers a statement and those nested in it.
+// Blocks are not registered, as only the statements in them are executed.
+func (c *Coverage) addStmt(f *File, fn *Func, s ast.Stmt) {
+	var decl *Func
+	switch node := s.(type) {
+	case nil:
+		return
+	case *ast.BlockStmt:
+		if node != nil {
+			c.addStmts(f, fn, node.Statements)
+		}
+		return
+	case *ast.IfStmt:
+		if node == nil {
+			return
+		}
+		if node.OnTrue != nil {
+			c.addStmts(f, fn, node.OnTrue.Statements)
+		}
+		if node.OnFalse != nil {
+			c.addStmts(f, fn, node.OnFalse.Statements)
+		}
+	case *ast.WhileStmt:
+		if node == nil {
+			return
+		}
+		if node.Body != nil {
+			c.addStmts(f, fn, node.Body.Statements)
+		}
+	case *ast.FuncDeclStmt:
+		if node == nil || node.Name == nil {
+			return
+		}
+		decl = &Func{Name: node.Name.String(), Line: node.Token.Line}
+		f.Funcs = append(f.Funcs, decl)
+		if node.Body != nil {
+			c.addStmts(f, decl, node.Body.Statements)
+		}
+	}
+	st := &stmt{line: ast.StmtToken(s).Line, fn: fn, decl: decl}
+	c.stmts[s] = st
+	f.stmts = append(f.stmts, st)
+	if fn != nil {
+		fn.stmts = append(fn.stmts, st)
+	}
+}
+
+func (c *Coverage) Enter(node ast.Node) {
+	s, isStmt := node.(ast.Stmt)
+	if !isStmt {
+		return
+	}
+	st, found := c.stmts[s]
+	if !found {
+		return
+	}
+	st.hits++
+	if c.calling && st.fn != nil {
+		st.fn.Calls++
+		c.calling = false
+	}
+	if st.decl != nil {
+		c.declared[st.decl.Name] = st.decl
+	}
+}
+
+func (c *Coverage) Exit(node ast.Node, val obj.Obj)      {}
+func (c *Coverage) Error(node ast.Node, err interface{}) {}
+
+func (c *Coverage) Call(name string, call token.Token, args []obj.Obj) {
+	c.calling = true
+}
+
+func (c *Coverage) Return(name string, val obj.Obj) {
+	if c.calling {
+		// the body has no statements, so find the function by name
+		if fn, found := c.declared[name]; found {
+			fn.Calls++
+		}
+		c.calling = false
+	}
+}
+
+func (c *Coverage) Bind(name string, val obj.Obj)   {}
+func (c *Coverage) Assign(name string, val obj.Obj) {}
+
+// Files returns the coverage of every program added, in order
+func (c *Coverage) Files() []*File {
+	return c.files
+}
+
+// Statements returns the number of statements in the file, and how many were executed
+func (f *File) Statements() (covered, total int) {
+	return countStmts(f.stmts)
+}
+
+// Statements returns the number of statements in the function's body,
+// outside nested functions, and how many were executed
+func (fn *Func) Statements() (covered, total int) {
+	return countStmts(fn.stmts)
+}
+
+func countStmts(stmts []*stmt) (covered, total int) {
+	for _, s := range stmts {
+		if s.hits > 0 {
+			covered++
+		}
+	}
+	return covered, len(stmts)
+}
+
+// Lines returns the number of times each line with statements was executed,
+// counting the most executed statement of a line. Lines are counted from 0.
+func (f *File) Lines() map[int]int {
+	lines := make(map[int]int)
+	for _, s := range f.stmts {
+		if hits, found := lines[s.line]; !found || s.hits > hits {
+			lines[s.line] = s.hits
+		}
+	}
+	return lines
+}
+
+// WriteLcov writes the coverage in the lcov tracefile format
+func (c *Coverage) WriteLcov(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range c.files {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.Path)
+		called := 0
+		for _, fn := range f.Funcs {
+			fmt.Fprintf(bw, "FN:%d,%s\n", fn.Line+1, fn.Name)
+		}
+		for _, fn := range f.Funcs {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", fn.Calls, fn.Name)
+			if fn.Calls > 0 {
+				called++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", len(f.Funcs), called)
+		lines := f.Lines()
+		nums := make([]int, 0, len(lines))
+		hit := 0
+		for line, hits := range lines {
+			nums = append(nums, line)
+			if hits > 0 {
+				hit++
+			}
+		}
+		sort.Ints(nums)
+		for _, line := range nums {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line+1, lines[line])
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
+
+// WriteSummary writes the percentage of statements executed in each file and function
+func (c *Coverage) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, f := range c.files {
+		covered, total := f.Statements()
+		fmt.Fprintf(tw, "%s\t%d/%d\t%s\n", f.Path, covered, total, percent(covered, total))
+		for _, fn := range f.Funcs {
+			covered, total := fn.Statements()
+			fmt.Fprintf(tw, "  %s (line %d)\t%d/%d\t%s\n", fn.Name, fn.Line+1, covered, total, percent(covered, total))
+		}
+	}
+	return tw.Flush()
+}
+
+func percent(covered, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
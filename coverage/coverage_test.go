@@ -0,0 +1,106 @@
+//go:build unit
+// +build unit
+
+package coverage
+
+import (
+	"bytes"
+	"golox/interp"
+	"golox/lexer"
+	"golox/parser"
+	"testing"
+)
+
+const source = `fun abs(n) {
+    if (n < 0) {
+        return -n;
+    } else {
+        return n;
+    }
+}
+fun unused() {
+    print "never";
+}
+fun empty() {}
+empty();
+abs(3);
+abs(4);
+`
+
+func cover(t *testing.T) *Coverage {
+	l := lexer.NewLexer(source)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	c := New()
+	c.Add("test.lox", prog)
+	intp := interp.New()
+	intp.Tracer = c
+	intp.Eval(prog)
+	return c
+}
+
+func TestStatements(t *testing.T) {
+	f := cover(t).Files()[0]
+	if covered, total := f.Statements(); covered != 8 || total != 10 {
+		t.Errorf("Expected 8/10 statements covered, got %d/%d", covered, total)
+	}
+	want := map[string][3]int{"abs": {2, 2, 3}, "unused": {0, 0, 1}, "empty": {1, 0, 0}}
+	for _, fn := range f.Funcs {
+		covered, total := fn.Statements()
+		if got := [3]int{fn.Calls, covered, total}; got != want[fn.Name] {
+			t.Errorf("Expected %s to have calls, covered and total statements %v, got %v", fn.Name, want[fn.Name], got)
+		}
+	}
+}
+
+func TestWriteLcov(t *testing.T) {
+	var out bytes.Buffer
+	if err := cover(t).WriteLcov(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `TN:
+SF:test.lox
+FN:1,abs
+FN:8,unused
+FN:11,empty
+FNDA:2,abs
+FNDA:0,unused
+FNDA:1,empty
+FNF:3
+FNH:2
+DA:1,1
+DA:2,2
+DA:3,0
+DA:5,2
+DA:8,1
+DA:9,0
+DA:11,1
+DA:12,1
+DA:13,1
+DA:14,1
+LF:10
+LH:8
+end_of_record
+`
+	if out.String() != want {
+		t.Fatalf("Expected lcov report:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	var out bytes.Buffer
+	if err := cover(t).WriteSummary(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `test.lox           8/10  80.0%
+  abs (line 1)     2/3   66.7%
+  unused (line 8)  0/1   0.0%
+  empty (line 11)  0/0   -
+`
+	if out.String() != want {
+		t.Fatalf("Expected summary:\n%s\ngot:\n%s", want, out.String())
+	}
+}
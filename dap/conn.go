@@ -0,0 +1,112 @@
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// Request is a request sent by the client
+type Request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Response answers a request
+type Response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+// Event is sent by the adapter to notify the client
+type Event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// Conn reads requests and writes responses and events framed by Content-Length headers
+type Conn struct {
+	r   *bufio.Reader
+	w   io.Writer
+	mu  sync.Mutex
+	seq int
+}
+
+// NewConn returns a Conn reading from r and writing to w
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read returns the next request
+func (c *Conn) Read() (*Request, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	req := &Request{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Reply sends a successful response to req
+func (c *Conn) Reply(req *Request, body interface{}) error {
+	return c.write(func(seq int) interface{} {
+		return &Response{seq, "response", req.Seq, true, req.Command, "", body}
+	})
+}
+
+// ReplyError sends a failed response to req
+func (c *Conn) ReplyError(req *Request, msg string) error {
+	return c.write(func(seq int) interface{} {
+		return &Response{seq, "response", req.Seq, false, req.Command, msg, nil}
+	})
+}
+
+// Event sends an event
+func (c *Conn) Event(event string, body interface{}) error {
+	return c.write(func(seq int) interface{} {
+		return &Event{seq, "event", event, body}
+	})
+}
+
+// Writes the message made with the next sequence number
+func (c *Conn) write(msg func(seq int) interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg(c.seq)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", body.Len()); err != nil {
+		return err
+	}
+	_, err := c.w.Write(body.Bytes())
+	return err
+}
@@ -0,0 +1,459 @@
+// Package dap implements a Debug Adapter Protocol server for Lox
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"golox/ast"
+	"golox/debug"
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"golox/stdlib"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The interpreter runs programs on a single thread
+const threadID = 1
+
+// Server debugs one program for one client
+type Server struct {
+	conn *Conn
+	dbg  *debug.Debugger
+	intp interp.Interpreter
+	prog *ast.Program
+	path string       // absolute path of the program
+	stmt map[int]bool // lines with statements, where breakpoints can be set
+
+	stopOnEntry bool
+	started     bool
+	done        chan struct{} // closed once the program has finished
+	cmds        chan command  // commands for the paused interpreter
+
+	mu          sync.Mutex
+	stop        *debug.Stop // where the program is paused, nil while running
+	refs        []obj.Env   // environments by variables reference, reset on every stop
+	terminating bool
+}
+
+// A command run by the interpreter's goroutine while paused
+type command struct {
+	run    func()       // code to run while staying paused
+	action debug.Action // how to resume, if run is nil
+}
+
+// NewServer returns a server reading requests from r and writing to w
+func NewServer(r io.Reader, w io.Writer) *Server {
+	s := &Server{
+		conn: NewConn(r, w),
+		intp: interp.New(),
+		done: make(chan struct{}),
+		cmds: make(chan command),
+	}
+	// everything the program prints is forwarded to the client
+	s.intp.Stdout = s.Output("stdout")
+	s.intp.Stderr = s.Output("stderr")
+	s.SetPermissions(stdlib.Defaults())
+	s.dbg = debug.New(s.paused)
+	s.dbg.Attach(&s.intp)
+	return s
+}
+
+// SetPermissions grants the program groups of builtin functions, instead of stdlib.Defaults,
+// and lets it import modules if they grant stdlib.Read
+func (s *Server) SetPermissions(perms stdlib.Permissions) {
+	stdlib.Install(&s.intp, perms)
+	stdlib.LimitImports(&s.intp, perms)
+}
+
+// SetImportPath sets the directories searched for the modules the program imports,
+// besides its own directory
+func (s *Server) SetImportPath(dirs []string) {
+	s.intp.ImportPath = dirs
+}
+
+// Output returns a writer sending everything written to it
+// to the client as output of the given category, such as "stdout"
+func (s *Server) Output(category string) io.Writer {
+	return outputWriter{s.conn, category}
+}
+
+type outputWriter struct {
+	conn     *Conn
+	category string
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	if err := w.conn.Event("output", OutputEventBody{w.category, string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Serve handles requests until the client disconnects
+func (s *Server) Serve() error {
+	for {
+		req, err := s.conn.Read()
+		if err == io.EOF {
+			s.terminate()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Command == "disconnect" {
+			s.terminate()
+			return s.conn.Reply(req, nil)
+		}
+		body, msg := s.handle(req)
+		if msg != "" {
+			err = s.conn.ReplyError(req, msg)
+		} else {
+			err = s.conn.Reply(req, body)
+		}
+		if err != nil {
+			return err
+		}
+		if req.Command == "launch" && msg == "" {
+			// ready for breakpoints now that the program is known
+			s.conn.Event("initialized", nil)
+		}
+	}
+}
+
+// Handles a request, returning the response body or an error message
+func (s *Server) handle(req *Request) (interface{}, string) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsConditionalBreakpoints:   true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, ""
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err.Error()
+		}
+		return nil, s.launch(args)
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err.Error()
+		}
+		return s.setBreakpoints(args), ""
+	case "configurationDone":
+		if s.prog == nil {
+			return nil, "No program has been launched."
+		}
+		if !s.started {
+			s.started = true
+			if s.stopOnEntry {
+				s.dbg.StopOnEntry()
+			}
+			go s.run()
+		}
+		return nil, ""
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{threadID, "main"}}}, ""
+	case "stackTrace":
+		stop := s.current()
+		if stop == nil {
+			return nil, "The program is not paused."
+		}
+		return map[string]interface{}{"stackFrames": s.stackFrames(stop)}, ""
+	case "scopes":
+		var args ScopesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err.Error()
+		}
+		frame, msg := s.frame(args.FrameID)
+		if msg != "" {
+			return nil, msg
+		}
+		return map[string]interface{}{"scopes": s.scopes(frame)}, ""
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err.Error()
+		}
+		return s.variables(args.VariablesReference)
+	case "evaluate":
+		var args EvaluateArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err.Error()
+		}
+		return s.evaluate(args)
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.resume(debug.Continue)
+	case "next":
+		return nil, s.resume(debug.StepOver)
+	case "stepIn":
+		return nil, s.resume(debug.StepIn)
+	case "stepOut":
+		return nil, s.resume(debug.StepOut)
+	case "pause":
+		s.dbg.Pause()
+		return nil, ""
+	case "terminate":
+		go s.terminate()
+		return nil, ""
+	}
+	return nil, fmt.Sprintf("Unsupported request %q.", req.Command)
+}
+
+// Loads and parses the program to debug
+func (s *Server) launch(args LaunchArguments) string {
+	path, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err.Error()
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	l := lexer.NewLexer(string(src))
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	var errs []string
+	for _, e := range l.Errors() {
+		errs = append(errs, e.Error())
+	}
+	for _, e := range p.Errors() {
+		errs = append(errs, fmt.Sprintf("[line %d:%d] %s", e.Token.Line, e.Token.LineOffset, e.Error()))
+	}
+	if len(errs) > 0 {
+		return strings.Join(errs, "\n")
+	}
+	s.prog, s.path, s.stopOnEntry = prog, path, args.StopOnEntry
+	s.intp.File = path
+	s.stmt = debug.StmtLines(prog)
+	return ""
+}
+
+// Replaces the breakpoints, verifying those on lines with statements
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) map[string]interface{} {
+	path, _ := filepath.Abs(args.Source.Path)
+	bps := make([]Breakpoint, len(args.Breakpoints))
+	var dbgBps []debug.Breakpoint
+	for i, sbp := range args.Breakpoints {
+		line := sbp.Line - 1
+		bps[i] = Breakpoint{Line: sbp.Line}
+		switch {
+		case path != s.path:
+			bps[i].Message = "Only breakpoints in the launched program are supported."
+		case !s.stmt[line]:
+			bps[i].Message = "No statement starts on this line."
+		default:
+			bps[i].Verified = true
+			dbgBps = append(dbgBps, debug.Breakpoint{Line: line, Condition: sbp.Condition})
+		}
+	}
+	if path == s.path {
+		s.dbg.SetBreakpoints(dbgBps)
+	}
+	return map[string]interface{}{"breakpoints": bps}
+}
+
+// Runs the program, reporting its exit to the client
+func (s *Server) run() {
+	defer close(s.done)
+	defer func() {
+		code := 0
+		if r := recover(); r != nil && r != debug.ErrAborted {
+			fmt.Fprintf(s.intp.Stderr, "Runtime Error: %v\n", r)
+			code = 70
+		}
+		s.conn.Event("exited", ExitedEventBody{code})
+		s.conn.Event("terminated", nil)
+	}()
+	s.intp.Eval(s.prog)
+}
+
+// Called by the debugger on the interpreter's goroutine whenever it stops.
+// Runs commands from requests until one resumes execution.
+func (s *Server) paused(stop *debug.Stop) debug.Action {
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return debug.Abort
+	}
+	s.stop, s.refs = stop, nil
+	s.mu.Unlock()
+	s.conn.Event("stopped", StoppedEventBody{Reason: stop.Reason, ThreadID: threadID, AllThreadsStopped: true})
+	for cmd := range s.cmds {
+		if cmd.run != nil {
+			cmd.run()
+			continue
+		}
+		s.mu.Lock()
+		s.stop, s.refs = nil, nil
+		s.mu.Unlock()
+		return cmd.action
+	}
+	return debug.Abort
+}
+
+// Returns where the program is paused, or nil while running
+func (s *Server) current() *debug.Stop {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop
+}
+
+// Resumes the paused program
+func (s *Server) resume(action debug.Action) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return "The program is not paused."
+	}
+	// requests from now on see the program running, even before it receives the action
+	s.stop, s.refs = nil, nil
+	// resume once the response has been sent, so it comes before the next stop
+	go func() { s.cmds <- command{action: action} }()
+	return ""
+}
+
+// Stops the program if it is running and waits for it to finish
+func (s *Server) terminate() {
+	if !s.started {
+		return
+	}
+	s.mu.Lock()
+	s.terminating = true
+	paused := s.stop != nil
+	s.mu.Unlock()
+	if paused {
+		s.cmds <- command{action: debug.Abort}
+	} else {
+		s.dbg.Pause()
+	}
+	<-s.done
+}
+
+// Returns the frame with the given ID in the paused call stack
+func (s *Server) frame(id int) (*interp.Frame, string) {
+	stop := s.current()
+	if stop == nil {
+		return nil, "The program is not paused."
+	}
+	frames := stop.Intp.CallStack()
+	if id < 1 || id > len(frames) {
+		return nil, fmt.Sprintf("Unknown frame %d.", id)
+	}
+	return frames[id-1], ""
+}
+
+// Returns the paused call stack, innermost first
+func (s *Server) stackFrames(stop *debug.Stop) []StackFrame {
+	source := Source{filepath.Base(s.path), s.path}
+	var frames []StackFrame
+	for i, f := range stop.Intp.CallStack() {
+		frame := StackFrame{ID: i + 1, Name: f.Name, Source: source}
+		if f.Stmt != nil {
+			tok := ast.StmtToken(f.Stmt)
+			frame.Line, frame.Column = tok.Line+1, tok.LineOffset+1
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// Returns every environment of a frame, innermost first
+func (s *Server) scopes(frame *interp.Frame) []Scope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var scopes []Scope
+	envs := frame.Intp.EnvStack
+	for i := len(envs) - 1; i >= 0; i-- {
+		name := fmt.Sprintf("Scope %d", i)
+		switch i {
+		case len(envs) - 1:
+			name = "Locals"
+		case 0:
+			name = "Globals"
+		}
+		s.refs = append(s.refs, envs[i])
+		scopes = append(scopes, Scope{Name: name, VariablesReference: len(s.refs)})
+	}
+	return scopes
+}
+
+// Returns the variables of the environment with the given reference
+func (s *Server) variables(ref int) (interface{}, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return nil, "The program is not paused."
+	}
+	if ref < 1 || ref > len(s.refs) {
+		return nil, fmt.Sprintf("Unknown variables reference %d.", ref)
+	}
+	env := s.refs[ref-1]
+	names := make([]string, 0, len(env.Bindings))
+	for name := range env.Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := []Variable{}
+	for _, name := range names {
+		val := *env.Bindings[name].Ref
+		vars = append(vars, Variable{Name: name, Value: fmt.Sprint(val), Type: typeName(val)})
+	}
+	return map[string]interface{}{"variables": vars}, ""
+}
+
+// Evaluates an expression in the scope of a paused frame
+func (s *Server) evaluate(args EvaluateArguments) (interface{}, string) {
+	intp := (*interp.Interpreter)(nil)
+	if args.FrameID == 0 {
+		stop := s.current()
+		if stop == nil {
+			return nil, "The program is not paused."
+		}
+		intp = stop.Intp
+	} else {
+		frame, msg := s.frame(args.FrameID)
+		if msg != "" {
+			return nil, msg
+		}
+		intp = frame.Intp
+	}
+	var val obj.Obj
+	var err error
+	done := make(chan struct{})
+	// the program may have finished since it was found paused
+	select {
+	case s.cmds <- command{run: func() {
+		val, err = debug.Eval(intp, args.Expression)
+		close(done)
+	}}:
+	case <-s.done:
+		return nil, "The program is not paused."
+	}
+	<-done
+	if err != nil {
+		return nil, err.Error()
+	}
+	result := ""
+	if val != nil {
+		result = val.String()
+	}
+	return map[string]interface{}{"result": result, "type": typeName(val), "variablesReference": 0}, ""
+}
+
+// Returns the name of the type of a Lox value
+func typeName(o obj.Obj) string {
+	if o == nil {
+		return ""
+	}
+	return o.Type().String()
+}
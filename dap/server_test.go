@@ -0,0 +1,291 @@
+//go:build unit
+// +build unit
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testMessage struct {
+	Type    string          `json:"type"`
+	Command string          `json:"command"`
+	Event   string          `json:"event"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// A client talking to a server over pipes
+type client struct {
+	t    *testing.T
+	w    io.Writer
+	seq  int
+	msgs chan testMessage
+}
+
+func newClient(t *testing.T) *client {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go NewServer(reqR, respW).Serve()
+	c := &client{t: t, w: reqW, msgs: make(chan testMessage, 100)}
+	go func() {
+		r := bufio.NewReader(respR)
+		for {
+			header, err := textproto.NewReader(r).ReadMIMEHeader()
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			body := make([]byte, length)
+			io.ReadFull(r, body)
+			var msg testMessage
+			json.Unmarshal(body, &msg)
+			c.msgs <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) send(command string, args interface{}) {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// Waits for a response to command or an event, skipping other messages
+func (c *client) expect(kind, name string, body interface{}) testMessage {
+	c.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatalf("Connection closed waiting for %s %s", kind, name)
+			}
+			if msg.Type != kind || msg.Command+msg.Event != name {
+				continue
+			}
+			if kind == "response" && !msg.Success {
+				c.t.Fatalf("Request %s failed: %s", name, msg.Message)
+			}
+			if body != nil {
+				json.Unmarshal(msg.Body, body)
+			}
+			return msg
+		case <-timeout:
+			c.t.Fatalf("Timed out waiting for %s %s", kind, name)
+		}
+	}
+}
+
+const source = `var total = 0;
+fun add(n) {
+    var doubled = n * 2;
+    total = total + doubled;
+}
+add(1);
+add(2);
+print total;
+`
+
+func TestDebugSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.send("initialize", map[string]interface{}{"adapterID": "lox"})
+	c.expect("response", "initialize", nil)
+	c.send("launch", LaunchArguments{Program: path})
+	c.expect("response", "launch", nil)
+	c.expect("event", "initialized", nil)
+
+	var bps struct{ Breakpoints []Breakpoint }
+	c.send("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: path},
+		Breakpoints: []SourceBreakpoint{{Line: 4, Condition: "n == 2"}, {Line: 5}},
+	})
+	c.expect("response", "setBreakpoints", &bps)
+	if len(bps.Breakpoints) != 2 || !bps.Breakpoints[0].Verified || bps.Breakpoints[1].Verified {
+		t.Fatalf("Expected only the first breakpoint to be verified, got %+v", bps.Breakpoints)
+	}
+	c.send("configurationDone", nil)
+	c.expect("response", "configurationDone", nil)
+
+	var stopped StoppedEventBody
+	c.expect("event", "stopped", &stopped)
+	if stopped.Reason != "breakpoint" {
+		t.Fatalf("Expected to stop at a breakpoint, got %q", stopped.Reason)
+	}
+
+	var trace struct{ StackFrames []StackFrame }
+	c.send("stackTrace", StackTraceArguments{ThreadID: 1})
+	c.expect("response", "stackTrace", &trace)
+	if len(trace.StackFrames) != 2 {
+		t.Fatalf("Expected 2 stack frames, got %+v", trace.StackFrames)
+	}
+	if f := trace.StackFrames[0]; f.Name != "add" || f.Line != 4 {
+		t.Fatalf("Expected to be in add on line 4, got %+v", f)
+	}
+	if f := trace.StackFrames[1]; f.Name != "<script>" || f.Line != 7 {
+		t.Fatalf("Expected to be called from line 7, got %+v", f)
+	}
+
+	var scopes struct{ Scopes []Scope }
+	c.send("scopes", ScopesArguments{FrameID: 1})
+	c.expect("response", "scopes", &scopes)
+	if len(scopes.Scopes) < 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[len(scopes.Scopes)-1].Name != "Globals" {
+		t.Fatalf("Expected Locals to Globals scopes, got %+v", scopes.Scopes)
+	}
+	var vars struct{ Variables []Variable }
+	c.send("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference})
+	c.expect("response", "variables", &vars)
+	if len(vars.Variables) != 1 || vars.Variables[0].Name != "doubled" || vars.Variables[0].Type != "number" {
+		t.Fatalf("Expected the local doubled, got %+v", vars.Variables)
+	}
+
+	var result struct{ Result string }
+	c.send("evaluate", EvaluateArguments{Expression: "total + n", FrameID: 1})
+	c.expect("response", "evaluate", &result)
+	if result.Result != "4.000000" {
+		t.Fatalf("Expected total + n to be 4, got %q", result.Result)
+	}
+
+	c.send("next", nil)
+	c.expect("response", "next", nil)
+	c.expect("event", "stopped", &stopped)
+	if stopped.Reason != "step" {
+		t.Fatalf("Expected to stop after a step, got %q", stopped.Reason)
+	}
+	c.send("stackTrace", StackTraceArguments{ThreadID: 1})
+	c.expect("response", "stackTrace", &trace)
+	if f := trace.StackFrames[0]; f.Name != "<script>" || f.Line != 8 {
+		t.Fatalf("Expected to step out of add to line 8, got %+v", f)
+	}
+
+	c.send("continue", nil)
+	c.expect("response", "continue", nil)
+	var exited ExitedEventBody
+	c.expect("event", "exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", exited.ExitCode)
+	}
+	c.expect("event", "terminated", nil)
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect", nil)
+}
+
+func TestEvaluateAfterContinue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.send("launch", LaunchArguments{Program: path})
+	c.expect("response", "launch", nil)
+	c.send("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 8}}})
+	c.expect("response", "setBreakpoints", nil)
+	c.send("configurationDone", nil)
+	c.expect("event", "stopped", nil)
+
+	// the program is running once continue is handled, so evaluate must neither wait
+	// for it to stop again nor block the requests after it once it exits
+	c.send("continue", nil)
+	c.send("evaluate", EvaluateArguments{Expression: "total"})
+	timeout := time.After(5 * time.Second)
+	for evaluated, exited := false, false; !evaluated || !exited; {
+		select {
+		case msg := <-c.msgs:
+			switch {
+			case msg.Command == "evaluate":
+				if msg.Success || msg.Message != "The program is not paused." {
+					t.Fatalf("Expected evaluate to fail as the program is running, got %+v", msg)
+				}
+				evaluated = true
+			case msg.Event == "exited":
+				exited = true
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for the evaluate response and the program to exit")
+		}
+	}
+	c.send("disconnect", nil)
+	c.expect("response", "disconnect", nil)
+}
+
+func TestRuntimeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte("print undefined;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.send("launch", LaunchArguments{Program: path})
+	c.expect("response", "launch", nil)
+	c.send("configurationDone", nil)
+	var output OutputEventBody
+	c.expect("event", "output", &output)
+	if output.Category != "stderr" {
+		t.Fatalf("Expected the error on stderr, got %+v", output)
+	}
+	var exited ExitedEventBody
+	c.expect("event", "exited", &exited)
+	if exited.ExitCode != 70 {
+		t.Fatalf("Expected exit code 70, got %d", exited.ExitCode)
+	}
+}
+
+func TestProgramOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte("print \"hello\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.send("launch", LaunchArguments{Program: path})
+	c.expect("response", "launch", nil)
+	c.send("configurationDone", nil)
+	var output OutputEventBody
+	c.expect("event", "output", &output)
+	if output.Category != "stdout" || output.Output != "hello\n" {
+		t.Fatalf("Expected the printed line on stdout, got %+v", output)
+	}
+	var exited ExitedEventBody
+	c.expect("event", "exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d", exited.ExitCode)
+	}
+}
+
+func TestLaunchParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte("var = ;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.send("launch", LaunchArguments{Program: path})
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-c.msgs:
+			if msg.Command != "launch" {
+				continue
+			}
+			if msg.Success || msg.Message == "" {
+				t.Fatalf("Expected launch to fail with a message, got %+v", msg)
+			}
+			return
+		case <-timeout:
+			t.Fatal("Timed out waiting for the launch response")
+		}
+	}
+}
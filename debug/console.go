@@ -0,0 +1,209 @@
+package debug
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/interp"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Console is a terminal debugger prompting for commands whenever execution stops
+type Console struct {
+	dbg   *Debugger
+	lines []string     // source lines, to show where execution stopped
+	stmts map[int]bool // lines with statements, where breakpoints can be set
+	read  func(prompt string) (string, error)
+	out   io.Writer
+	last  string // last command, repeated by an empty line
+}
+
+// NewConsole returns a Console debugging prog, parsed from src.
+// Commands are read with read and everything is printed to out.
+func NewConsole(prog *ast.Program, src string, read func(prompt string) (string, error), out io.Writer) *Console {
+	c := &Console{
+		lines: strings.Split(src, "\n"),
+		stmts: StmtLines(prog),
+		read:  read,
+		out:   out,
+	}
+	c.dbg = New(c.paused)
+	return c
+}
+
+// Debugger returns the debugger controlled by the console
+func (c *Console) Debugger() *Debugger {
+	return c.dbg
+}
+
+// Break sets a breakpoint on a line, counted from 1
+func (c *Console) Break(line int) error {
+	if !c.stmts[line-1] {
+		return fmt.Errorf("No statement starts on line %d.", line)
+	}
+	c.dbg.AddBreakpoint(Breakpoint{Line: line - 1})
+	return nil
+}
+
+// Run evaluates prog with intp under the debugger.
+// Returns ErrAborted if the user quit, or the runtime error stopping the program.
+func (c *Console) Run(intp *interp.Interpreter, prog *ast.Program) (err error) {
+	c.dbg.Attach(intp)
+	defer func() {
+		if r := recover(); r != nil {
+			if r == ErrAborted {
+				err = ErrAborted
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	intp.Eval(prog)
+	return nil
+}
+
+const consoleHelp = `Commands:
+  s, step           step into the next statement
+  n, next           step over calls to the next statement
+  f, finish         run until the current function returns
+  c, continue       run until the next breakpoint or debugger statement
+  p, print <expr>   evaluate an expression in the paused scope
+  l, locals         show the variables of the current function
+  bt, backtrace     show the call stack
+  b, break [line]   set a breakpoint on a line, or list the breakpoints
+  q, quit           stop the program
+An empty line repeats the last command.
+`
+
+// Shows where execution stopped and runs commands until one resumes it
+func (c *Console) paused(stop *Stop) Action {
+	line := ast.StmtToken(stop.Stmt).Line
+	frame := "<script>"
+	if frames := stop.Intp.CallStack(); len(frames) > 0 {
+		frame = frames[0].Name
+	}
+	fmt.Fprintf(c.out, "Stopped (%s) in %s at line %d:\n", stop.Reason, frame, line+1)
+	c.showLine(line)
+	for {
+		input, err := c.read("(debug) ")
+		if err != nil {
+			return Abort
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = c.last
+		}
+		c.last = input
+		if input == "" {
+			continue
+		}
+		cmd, arg := input, ""
+		if i := strings.IndexAny(input, " \t"); i >= 0 {
+			cmd, arg = input[:i], strings.TrimSpace(input[i:])
+		}
+		switch cmd {
+		case "s", "step":
+			return StepIn
+		case "n", "next":
+			return StepOver
+		case "f", "finish":
+			return StepOut
+		case "c", "continue":
+			return Continue
+		case "q", "quit":
+			return Abort
+		case "p", "print":
+			c.print(stop, arg)
+		case "l", "locals":
+			c.locals(stop)
+		case "bt", "backtrace":
+			c.backtrace(stop)
+		case "b", "break":
+			c.breakCmd(arg)
+		case "h", "help":
+			fmt.Fprint(c.out, consoleHelp)
+		default:
+			fmt.Fprintf(c.out, "Unknown command %q. Type help for a list of commands.\n", cmd)
+		}
+	}
+}
+
+// Prints a source line, counted from 0, with its number
+func (c *Console) showLine(line int) {
+	if line >= 0 && line < len(c.lines) {
+		fmt.Fprintf(c.out, "%5d | %s\n", line+1, c.lines[line])
+	}
+}
+
+func (c *Console) print(stop *Stop, expr string) {
+	if expr == "" {
+		fmt.Fprintln(c.out, "Usage: print <expr>")
+		return
+	}
+	val, err := stop.Eval(expr)
+	if err != nil {
+		fmt.Fprintf(c.out, "Error: %s\n", err)
+	} else if val != nil {
+		fmt.Fprintln(c.out, val)
+	}
+}
+
+// Prints the variables of every scope of the paused function, innermost first.
+// At the top level there is only the global scope.
+func (c *Console) locals(stop *Stop) {
+	envs := stop.Intp.EnvStack
+	outermost := 1
+	if len(envs) == 1 {
+		outermost = 0
+	}
+	found := false
+	for i := len(envs) - 1; i >= outermost; i-- {
+		names := make([]string, 0, len(envs[i].Bindings))
+		for name := range envs[i].Bindings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.out, "%s = %s\n", name, *envs[i].Bindings[name].Ref)
+			found = true
+		}
+	}
+	if !found {
+		fmt.Fprintln(c.out, "No local variables.")
+	}
+}
+
+func (c *Console) backtrace(stop *Stop) {
+	for i, f := range stop.Intp.CallStack() {
+		line := 0
+		if f.Stmt != nil {
+			line = ast.StmtToken(f.Stmt).Line
+		}
+		fmt.Fprintf(c.out, "#%d %s at line %d\n", i, f.Name, line+1)
+	}
+}
+
+func (c *Console) breakCmd(arg string) {
+	if arg == "" {
+		bps := c.dbg.Breakpoints()
+		if len(bps) == 0 {
+			fmt.Fprintln(c.out, "No breakpoints.")
+		}
+		for _, bp := range bps {
+			fmt.Fprintf(c.out, "Breakpoint at line %d\n", bp.Line+1)
+		}
+		return
+	}
+	line, err := strconv.Atoi(arg)
+	if err != nil {
+		fmt.Fprintf(c.out, "Invalid line number %q.\n", arg)
+		return
+	}
+	if err := c.Break(line); err != nil {
+		fmt.Fprintln(c.out, err)
+		return
+	}
+	fmt.Fprintf(c.out, "Breakpoint set at line %d.\n", line)
+}
@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package debug
+
+import (
+	"bytes"
+	"golox/interp"
+	"io"
+	"strings"
+	"testing"
+)
+
+const consoleSource = `var total = 0;
+fun add(n) {
+    var doubled = n * 2;
+    debugger;
+    total = total + doubled;
+}
+add(1);
+print total;
+`
+
+// Runs consoleSource in a console fed with commands, returning its output
+func runConsole(t *testing.T, commands ...string) (string, error) {
+	var out bytes.Buffer
+	read := func(prompt string) (string, error) {
+		if len(commands) == 0 {
+			return "", io.EOF
+		}
+		cmd := commands[0]
+		commands = commands[1:]
+		return cmd, nil
+	}
+	c := NewConsole(parse(t, consoleSource), consoleSource, read, &out)
+	intp := interp.New()
+	err := c.Run(&intp, parse(t, consoleSource))
+	return out.String(), err
+}
+
+func assertContains(t *testing.T, out string, want ...string) {
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("Expected output to contain %q, got:\n%s", w, out)
+		}
+	}
+}
+
+func TestConsoleDebuggerStmt(t *testing.T) {
+	out, err := runConsole(t, "print n + 1", "locals", "backtrace", "continue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, out,
+		"Stopped (debugger statement) in add at line 4:",
+		"    4 |     debugger;",
+		"2.000000",
+		"doubled = 2.000000",
+		"#0 add at line 4\n#1 <script> at line 7\n")
+}
+
+func TestConsoleStepping(t *testing.T) {
+	out, err := runConsole(t, "next", "", "finish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, out,
+		"Stopped (step) in add at line 5:",
+		"Stopped (step) in <script> at line 8:")
+}
+
+func TestConsoleBreak(t *testing.T) {
+	out, err := runConsole(t, "break 5", "break 2", "break 30", "break", "c", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, out,
+		"Breakpoint set at line 5.",
+		"Breakpoint set at line 2.",
+		"No statement starts on line 30.",
+		"Breakpoint at line 2\nBreakpoint at line 5\n",
+		"Stopped (breakpoint) in add at line 5:")
+}
+
+func TestConsoleQuit(t *testing.T) {
+	if _, err := runConsole(t, "quit"); err != ErrAborted {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+	if _, err := runConsole(t); err != ErrAborted {
+		t.Fatalf("Expected ErrAborted at the end of input, got %v", err)
+	}
+}
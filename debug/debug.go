@@ -0,0 +1,227 @@
+// Package debug pauses the interpreter at breakpoints and steps through statements
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"golox/ast"
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrAborted is panicked with by the interpreter when the Abort action is chosen
+var ErrAborted = errors.New("execution aborted by the debugger")
+
+// Action is how to resume execution after a stop
+type Action uint8
+
+const (
+	Continue Action = iota // run until the next breakpoint
+	StepIn                 // stop at the next statement
+	StepOver               // stop at the next statement of this or an outer call
+	StepOut                // stop at the next statement of an outer call
+	Abort                  // stop executing the program
+)
+
+// Reasons for stopping
+const (
+	ReasonEntry      = "entry"
+	ReasonBreakpoint = "breakpoint"
+	ReasonStep       = "step"
+	ReasonPause      = "pause"
+	ReasonDebugger   = "debugger statement"
+)
+
+// Breakpoint stops execution before statements starting on a line
+type Breakpoint struct {
+	Line      int    // line of the statement, counted from 0 like token lines
+	Condition string // expression that must be truthy to stop, or empty to always stop
+}
+
+// Stop is a point where execution is paused
+type Stop struct {
+	Reason string
+	Intp   *interp.Interpreter // innermost interpreter, evaluating Stmt
+	Stmt   ast.Stmt            // statement about to be evaluated
+}
+
+// Eval evaluates Lox source in the scope of the paused statement.
+// The value of a single expression is returned, otherwise nil.
+func (s *Stop) Eval(src string) (obj.Obj, error) {
+	return Eval(s.Intp, src)
+}
+
+// Eval evaluates Lox source with intp without stopping at any breakpoint.
+// The value of a single expression is returned, otherwise nil.
+func Eval(intp *interp.Interpreter, src string) (val obj.Obj, err error) {
+	src = strings.TrimSpace(src)
+	if !strings.HasSuffix(src, ";") && !strings.HasSuffix(src, "}") {
+		src += ";"
+	}
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if es := p.Errors(); len(es) > 0 {
+		return nil, es[0]
+	}
+
+	onStmt := intp.OnStmt
+	intp.OnStmt = nil
+	defer func() {
+		intp.OnStmt = onStmt
+		if r := recover(); r != nil {
+			val, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	for _, stmt := range prog.Statements {
+		val = intp.Eval(stmt)
+	}
+	if len(prog.Statements) != 1 {
+		return nil, nil
+	}
+	if _, isExpr := prog.Statements[0].(*ast.ExprStmt); !isExpr {
+		return nil, nil
+	}
+	return val, nil
+}
+
+// Debugger decides where to stop an interpreter and how to resume it
+type Debugger struct {
+	mu          sync.Mutex
+	breakpoints map[int][]Breakpoint
+	action      Action
+	depth       int  // call depth when the action was chosen
+	entry       bool // stop before the first statement
+	pause       bool // stop before the next statement
+	paused      func(stop *Stop) Action
+}
+
+// New returns a Debugger calling paused whenever execution stops.
+// paused is called on the interpreter's goroutine, which stays
+// paused until it returns how to resume.
+func New(paused func(stop *Stop) Action) *Debugger {
+	return &Debugger{breakpoints: make(map[int][]Breakpoint), paused: paused}
+}
+
+// Attach makes the debugger control intp and every call it makes
+func (d *Debugger) Attach(intp *interp.Interpreter) {
+	intp.OnStmt = d.onStmt
+}
+
+// StopOnEntry makes the debugger stop before the first statement
+func (d *Debugger) StopOnEntry() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entry = true
+}
+
+// Pause makes the debugger stop before the next statement.
+// It is safe to call from any goroutine.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pause = true
+}
+
+// SetBreakpoints replaces every breakpoint
+func (d *Debugger) SetBreakpoints(bps []Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[int][]Breakpoint)
+	for _, bp := range bps {
+		d.breakpoints[bp.Line] = append(d.breakpoints[bp.Line], bp)
+	}
+}
+
+// AddBreakpoint adds a breakpoint to the existing ones
+func (d *Debugger) AddBreakpoint(bp Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[bp.Line] = append(d.breakpoints[bp.Line], bp)
+}
+
+// Breakpoints returns every breakpoint, ordered by line
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var bps []Breakpoint
+	for _, lineBps := range d.breakpoints {
+		bps = append(bps, lineBps...)
+	}
+	sort.SliceStable(bps, func(i, j int) bool { return bps[i].Line < bps[j].Line })
+	return bps
+}
+
+// Called before every statement, stopping if needed
+func (d *Debugger) onStmt(intp *interp.Interpreter, stmt ast.Stmt) {
+	depth := len(intp.CallStack())
+	line := ast.StmtToken(stmt).Line
+
+	d.mu.Lock()
+	reason := ""
+	switch {
+	case d.entry:
+		reason = ReasonEntry
+	case d.pause:
+		reason = ReasonPause
+	case isDebuggerStmt(stmt):
+		reason = ReasonDebugger
+	case d.action == StepIn,
+		d.action == StepOver && depth <= d.depth,
+		d.action == StepOut && depth < d.depth:
+		reason = ReasonStep
+	}
+	bps := d.breakpoints[line]
+	d.mu.Unlock()
+
+	stop := &Stop{Reason: reason, Intp: intp, Stmt: stmt}
+	if reason == "" {
+		for _, bp := range bps {
+			if bp.Condition == "" {
+				reason = ReasonBreakpoint
+				break
+			}
+			val, err := stop.Eval(bp.Condition)
+			if err != nil || interp.IsTruthy(val) {
+				// a broken condition stops so it can be fixed
+				reason = ReasonBreakpoint
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return
+	}
+	stop.Reason = reason
+	action := d.paused(stop)
+
+	d.mu.Lock()
+	d.action, d.depth = action, depth
+	d.entry, d.pause = false, false
+	d.mu.Unlock()
+	if action == Abort {
+		panic(ErrAborted)
+	}
+}
+
+func isDebuggerStmt(stmt ast.Stmt) bool {
+	_, isDebugger := stmt.(*ast.DebuggerStmt)
+	return isDebugger
+}
+
+// StmtLines returns every line a statement of prog starts on
+func StmtLines(prog *ast.Program) map[int]bool {
+	lines := make(map[int]bool)
+	ast.Inspect(prog, func(n ast.Node) bool {
+		if stmt, isStmt := n.(ast.Stmt); isStmt {
+			lines[ast.StmtToken(stmt).Line] = true
+		}
+		return true
+	})
+	return lines
+}
@@ -0,0 +1,139 @@
+//go:build unit
+// +build unit
+
+package debug
+
+import (
+	"golox/ast"
+	"golox/interp"
+	"golox/lexer"
+	"golox/parser"
+	"testing"
+)
+
+const source = `var total = 0;
+fun add(n) {
+    total = total + n;
+    return total;
+}
+add(1);
+add(2);
+print total;
+`
+
+func parse(t *testing.T, src string) *ast.Program {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	return prog
+}
+
+// Runs source, choosing actions in order at each stop and returning the stops' lines
+func run(t *testing.T, d *Debugger, onStop func(*Stop), actions ...Action) []int {
+	var lines []int
+	d.paused = func(stop *Stop) Action {
+		lines = append(lines, ast.StmtToken(stop.Stmt).Line)
+		if onStop != nil {
+			onStop(stop)
+		}
+		if len(lines) > len(actions) {
+			return Continue
+		}
+		return actions[len(lines)-1]
+	}
+	intp := interp.New()
+	d.Attach(&intp)
+	intp.Eval(parse(t, source))
+	return lines
+}
+
+func assertLines(t *testing.T, got []int, want ...int) {
+	if len(got) != len(want) {
+		t.Fatalf("Expected stops on lines %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected stops on lines %v, got %v", want, got)
+		}
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	d := New(nil)
+	d.AddBreakpoint(Breakpoint{Line: 2})
+	assertLines(t, run(t, d, nil), 2, 2)
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	d := New(nil)
+	d.AddBreakpoint(Breakpoint{Line: 2, Condition: "n == 2"})
+	var total string
+	lines := run(t, d, func(stop *Stop) {
+		val, err := stop.Eval("total")
+		if err != nil {
+			t.Fatal(err)
+		}
+		total = val.String()
+	})
+	assertLines(t, lines, 2)
+	if total != "1.000000" {
+		t.Fatalf("Expected total to be 1 at the second call, got %s", total)
+	}
+}
+
+func TestStepping(t *testing.T) {
+	d := New(nil)
+	d.StopOnEntry()
+	assertLines(t, run(t, d, nil, StepOver, StepOver, StepIn, StepOut, StepOver),
+		0, 1, 5, 2, 6, 7)
+}
+
+func TestStepOverCall(t *testing.T) {
+	d := New(nil)
+	d.AddBreakpoint(Breakpoint{Line: 5})
+	assertLines(t, run(t, d, nil, StepOver, StepOver), 5, 6, 7)
+}
+
+func TestCallStack(t *testing.T) {
+	d := New(nil)
+	d.AddBreakpoint(Breakpoint{Line: 3})
+	var names []string
+	run(t, d, func(stop *Stop) {
+		if names != nil {
+			return
+		}
+		for _, f := range stop.Intp.CallStack() {
+			names = append(names, f.Name)
+		}
+	})
+	if len(names) != 2 || names[0] != "add" || names[1] != "<script>" {
+		t.Fatalf("Expected call stack [add <script>], got %v", names)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	d := New(nil)
+	d.StopOnEntry()
+	defer func() {
+		if r := recover(); r != ErrAborted {
+			t.Fatalf("Expected ErrAborted, got %v", r)
+		}
+	}()
+	run(t, d, nil, Abort)
+	t.Fatal("Expected the program to be aborted")
+}
+
+func TestStmtLines(t *testing.T) {
+	lines := StmtLines(parse(t, source))
+	for _, line := range []int{0, 1, 2, 3, 5, 6, 7} {
+		if !lines[line] {
+			t.Errorf("Expected a statement on line %d", line)
+		}
+	}
+	if lines[4] {
+		t.Errorf("Expected no statement on line 4")
+	}
+}
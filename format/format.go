@@ -0,0 +1,327 @@
+// Package format implements canonical source formatting that preserves comments
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"golox/token"
+	"strings"
+)
+
+// Indentation used for each level of block nesting
+const indent = "    "
+
+// ParseError holds every error encountered while parsing the source to format
+type ParseError struct {
+	Errors []parser.ParserError
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Source returns the canonical formatting of src.
+// Formatting is idempotent, so formatting the result again changes nothing.
+func Source(src string) (string, error) {
+	prog, comments, err := parse(src)
+	if err != nil {
+		return "", err
+	}
+	out := Program(prog, comments, src)
+
+	// make sure formatting never changes what the program means
+	formatted, formattedComments, err := parse(out)
+	if err != nil || formatted.String() != prog.String() || len(formattedComments) != len(comments) {
+		return "", errors.New("formatting would change the meaning of the program")
+	}
+	return out, nil
+}
+
+// Returns the program and comments of src
+func parse(src string) (*ast.Program, []token.Token, error) {
+	l := lexer.NewLexer(src)
+	l.KeepComments = true
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, nil, &ParseError{p.Errors()}
+	}
+	return prog, p.Comments(), nil
+}
+
+// Program formats prog, placing comments according to their positions in src
+func Program(prog *ast.Program, comments []token.Token, src string) string {
+	pr := &printer{
+		lines:      strings.Split(src, "\n"),
+		comments:   comments,
+		blockStart: true,
+	}
+	for _, stmt := range prog.Statements {
+		pr.stmt(stmt)
+	}
+	pr.flushComments(-1)
+	return pr.out.String()
+}
+
+type printer struct {
+	out        bytes.Buffer
+	depth      int
+	lines      []string      // source lines, to find blank lines and trailing comments
+	comments   []token.Token // comments not yet printed, in source order
+	blockStart bool          // nothing printed yet in the current block
+}
+
+// Writes a full line at the current indentation,
+// preceded by a blank line if line in the source was
+func (pr *printer) line(srcLine int, text string) {
+	if !pr.blockStart && pr.blankBefore(srcLine) {
+		pr.out.WriteString("\n")
+	}
+	pr.blockStart = false
+	pr.out.WriteString(strings.Repeat(indent, pr.depth))
+	pr.out.WriteString(text)
+	pr.out.WriteString("\n")
+}
+
+// Returns if the source line before srcLine is empty
+func (pr *printer) blankBefore(srcLine int) bool {
+	return srcLine > 0 && srcLine <= len(pr.lines) && strings.TrimSpace(pr.lines[srcLine-1]) == ""
+}
+
+// Returns if a comment shares its line with code before it
+func (pr *printer) isTrailing(c token.Token) bool {
+	if c.Line >= len(pr.lines) {
+		return false
+	}
+	before := pr.lines[c.Line]
+	if c.LineOffset <= len(before) {
+		before = before[:c.LineOffset]
+	}
+	return strings.TrimSpace(before) != ""
+}
+
+// Prints every pending comment starting before the line srcLine,
+// or every pending comment if srcLine is negative
+func (pr *printer) flushComments(srcLine int) {
+	for len(pr.comments) > 0 && (srcLine < 0 || pr.comments[0].Line < srcLine) {
+		c := pr.comments[0]
+		pr.comments = pr.comments[1:]
+		text := strings.TrimRight(c.Lexeme, " \t\r")
+		if pr.isTrailing(c) && pr.out.Len() > 0 {
+			// attach to the end of the last printed line
+			pr.out.Truncate(pr.out.Len() - 1)
+			pr.out.WriteString(" " + text + "\n")
+			continue
+		}
+		pr.line(c.Line, text)
+	}
+}
+
+// Prints a statement along with the comments before it
+func (pr *printer) stmt(stmt ast.Stmt) {
+	srcLine := ast.StmtToken(stmt).Line
+	pr.flushComments(srcLine)
+	// comments within code printed on a single line cannot stay where they are,
+	// so they go on their own lines before it rather than after the statement
+	for end := headEnd(stmt); len(pr.comments) > 0 && pr.comments[0].Line < end; {
+		c := pr.comments[0]
+		pr.comments = pr.comments[1:]
+		pr.line(c.Line, strings.TrimRight(c.Lexeme, " \t\r"))
+	}
+	switch stmt := stmt.(type) {
+	case *ast.ExprStmt:
+		pr.line(srcLine, Expr(stmt.Expr)+";")
+	case *ast.PrintStmt:
+		pr.line(srcLine, "print "+Expr(stmt.Expr)+";")
+	case *ast.AssignStmt:
+		pr.line(srcLine, stmt.Name.String()+" = "+Expr(stmt.Expr)+";")
+	case *ast.SetStmt:
+		pr.line(srcLine, Expr(&ast.GetExpr{Object: stmt.Object, Name: stmt.Name})+" = "+Expr(stmt.Value)+";")
+	case *ast.VarStmt:
+		pr.line(srcLine, "var "+stmt.Name.String()+ast.Annotation(stmt.Type)+" = "+Expr(stmt.Value)+";")
+	case *ast.DebuggerStmt:
+		pr.line(srcLine, "debugger;")
+	case *ast.ImportStmt:
+		pr.line(srcLine, stmt.String())
+	case *ast.ReturnStmt:
+		if stmt.ReturnValue == nil {
+			pr.line(srcLine, "return;")
+		} else {
+			pr.line(srcLine, "return "+Expr(stmt.ReturnValue)+";")
+		}
+	case *ast.FuncDeclStmt:
+		params := make([]string, len(stmt.Params))
+		for i, param := range stmt.Params {
+			params[i] = param.String() + ast.Annotation(stmt.ParamType(i))
+		}
+		head := "fun " + stmt.Name.String() + "(" + strings.Join(params, ", ") + ")" + ast.Annotation(stmt.ReturnType) + " "
+		pr.block(srcLine, head, stmt.Body)
+	case *ast.BlockStmt:
+		pr.block(srcLine, "", stmt)
+	case *ast.IfStmt:
+		pr.block(srcLine, "if "+Expr(stmt.Cond)+" ", stmt.OnTrue)
+		if stmt.OnFalse != nil {
+			// continue on the line of the closing brace
+			pr.out.Truncate(pr.out.Len() - 1)
+			if pr.isEmpty(stmt.OnFalse) {
+				pr.out.WriteString(" else {}\n")
+			} else {
+				pr.out.WriteString(" else {\n")
+				pr.body(stmt.OnFalse)
+			}
+		}
+	case *ast.WhileStmt:
+		pr.block(srcLine, "while "+Expr(stmt.Cond)+" ", stmt.Body)
+	default:
+		panic(fmt.Sprintf("Unable to format unexpected statement, got: %T", stmt))
+	}
+}
+
+// Returns the source line where the code a statement prints on its first line ends:
+// its last token for simple statements, or the opening brace of its block
+func headEnd(stmt ast.Stmt) int {
+	switch stmt := stmt.(type) {
+	case *ast.FuncDeclStmt:
+		return stmt.Body.Token.Line
+	case *ast.IfStmt:
+		return stmt.OnTrue.Token.Line
+	case *ast.WhileStmt:
+		return stmt.Body.Token.Line
+	case *ast.BlockStmt:
+		return stmt.Token.Line
+	}
+	end := ast.StmtToken(stmt).Line
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		var tok token.Token
+		switch node := node.(type) {
+		case ast.Identifier:
+			tok = node.Token
+		case *ast.Identifier:
+			tok = node.Token
+		case ast.NumExpr:
+			tok = node.Token
+		case ast.StrExpr:
+			tok = node.Token
+		case ast.NilExpr:
+			tok = node.Token
+		case ast.BoolExpr:
+			tok = node.Token
+		case *ast.ImportStmt:
+			tok = node.Path
+		}
+		// strings can span lines
+		if last := tok.Line + strings.Count(tok.Lexeme, "\n"); last > end {
+			end = last
+		}
+		return true
+	})
+	return end
+}
+
+// Prints a block whose opening brace ends the line starting with head
+func (pr *printer) block(srcLine int, head string, bs *ast.BlockStmt) {
+	if pr.isEmpty(bs) {
+		pr.line(srcLine, head+"{}")
+		return
+	}
+	pr.line(srcLine, head+"{")
+	pr.body(bs)
+}
+
+// Prints the statements of a block and its closing brace
+func (pr *printer) body(bs *ast.BlockStmt) {
+	pr.depth++
+	pr.blockStart = true
+	for _, stmt := range bs.Statements {
+		pr.stmt(stmt)
+	}
+	pr.flushComments(bs.EndToken.Line)
+	pr.depth--
+	pr.blockStart = true // never leave a blank line before a closing brace
+	pr.line(bs.EndToken.Line, "}")
+}
+
+// Returns if a block has neither statements nor comments on their own line
+func (pr *printer) isEmpty(bs *ast.BlockStmt) bool {
+	if len(bs.Statements) > 0 {
+		return false
+	}
+	for _, c := range pr.comments {
+		if c.Line >= bs.EndToken.Line {
+			break
+		}
+		if !pr.isTrailing(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expr returns the canonical formatting of an expression,
+// with only the parentheses needed to keep its meaning
+func Expr(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case ast.Identifier:
+		return expr.Token.Lexeme
+	case ast.NumExpr:
+		return expr.Token.Lexeme
+	case ast.NilExpr:
+		return expr.Token.Lexeme
+	case ast.StrExpr:
+		return expr.Token.Lexeme
+	case ast.BoolExpr:
+		return expr.Token.Lexeme
+	case *ast.PrefixExpr:
+		right := Expr(expr.Right)
+		if _, isInfix := expr.Right.(*ast.InfixExpr); isInfix {
+			right = "(" + right + ")"
+		}
+		return expr.Token.Lexeme + right
+	case *ast.InfixExpr:
+		prec := parser.PrecedenceOf(expr.Token.Type)
+		left, right := Expr(expr.Left), Expr(expr.Right)
+		// operators are left-associative, so an equal precedence
+		// on the right needs grouping but on the left does not
+		if l, isInfix := expr.Left.(*ast.InfixExpr); isInfix && parser.PrecedenceOf(l.Token.Type) < prec {
+			left = "(" + left + ")"
+		}
+		if r, isInfix := expr.Right.(*ast.InfixExpr); isInfix && parser.PrecedenceOf(r.Token.Type) <= prec {
+			right = "(" + right + ")"
+		}
+		return left + " " + expr.Token.Lexeme + " " + right
+	case *ast.CallExpr:
+		return expr.Function.String() + args(expr.Args)
+	case *ast.GetExpr:
+		return object(expr.Object) + "." + expr.Name.String()
+	case *ast.MethodCallExpr:
+		return object(expr.Object) + "." + expr.Name.String() + args(expr.Args)
+	}
+	panic(fmt.Sprintf("Unable to format unexpected expression, got: %T", expr))
+}
+
+// Returns the formatting of the arguments of a call, in parentheses
+func args(exprs []ast.Expr) string {
+	args := make([]string, len(exprs))
+	for i, arg := range exprs {
+		args[i] = Expr(arg)
+	}
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// Returns the formatting of the object before a property,
+// grouped if it is an operation
+func object(expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.PrefixExpr, *ast.InfixExpr:
+		return "(" + Expr(expr) + ")"
+	}
+	return Expr(expr)
+}
@@ -0,0 +1,74 @@
+//go:build unit
+// +build unit
+
+package format
+
+import (
+	"testing"
+)
+
+func testFormat(t *testing.T, input string, expected string) {
+	out, err := Source(input)
+	if err != nil {
+		t.Fatalf("Unexpected error formatting %q: %s", input, err)
+	}
+	if out != expected {
+		t.Fatalf("Formatting %q: expected\n%s\ngot\n%s", input, expected, out)
+	}
+	again, err := Source(out)
+	if err != nil {
+		t.Fatalf("Unexpected error reformatting %q: %s", out, err)
+	}
+	if again != out {
+		t.Fatalf("Formatting is not idempotent: expected\n%s\ngot\n%s", out, again)
+	}
+}
+
+func TestFormatStatements(t *testing.T) {
+	testFormat(t, "var   x=1;x=x+1 ;print x;  x;", "var x = 1;\nx = x + 1;\nprint x;\nx;\n")
+	testFormat(t, "fun f(a,b){return a;}", "fun f(a, b) {\n    return a;\n}\n")
+	testFormat(t, "fun f(){return;}", "fun f() {\n    return;\n}\n")
+	testFormat(t, "while x<1 {x=x+1;}", "while x < 1 {\n    x = x + 1;\n}\n")
+	testFormat(t, "if (x) {} else {print x;}", "if x {} else {\n    print x;\n}\n")
+	testFormat(t, "{{print 1;}}", "{\n    {\n        print 1;\n    }\n}\n")
+	testFormat(t, "var x :Num=1;fun f(a:Str,b):Bool{}", "var x: Num = 1;\nfun f(a: Str, b): Bool {}\n")
+}
+
+func TestFormatParentheses(t *testing.T) {
+	testFormat(t, "(1+2)*3;", "(1 + 2) * 3;\n")
+	testFormat(t, "1+(2*3);", "1 + 2 * 3;\n")
+	testFormat(t, "(1-2)-3;", "1 - 2 - 3;\n")
+	testFormat(t, "1-(2-3);", "1 - (2 - 3);\n")
+	testFormat(t, "-(1+2);", "-(1 + 2);\n")
+	testFormat(t, "!(f(1, (2)));", "!f(1, 2);\n")
+	testFormat(t, "(a.b).c;", "a.b.c;\n")
+	testFormat(t, "(-a).b ;-a.b;", "(-a).b;\n-a.b;\n")
+	testFormat(t, "(a+b).m( 1,(2) ).c=x .y;", "(a + b).m(1, 2).c = x.y;\n")
+}
+
+func TestFormatComments(t *testing.T) {
+	testFormat(t, "// head\nvar x = 1; // tail\n", "// head\nvar x = 1; // tail\n")
+	testFormat(t, "fun f() {\n// inner\n}\n", "fun f() {\n    // inner\n}\n")
+	testFormat(t, "fun f() { // tail\n}\n", "fun f() {} // tail\n")
+	testFormat(t, "{\nx;\n   // last\n}\n// end", "{\n    x;\n    // last\n}\n// end\n")
+}
+
+func TestFormatCommentsInStatements(t *testing.T) {
+	// comments cannot stay inside code printed on one line, so they move before it
+	testFormat(t, "f(a, // first\n  b,\n  // between\n  c);\nx;\n", "// first\n// between\nf(a, b, c);\nx;\n")
+	testFormat(t, "var x = 1 +\n    // two\n    2; // tail\n", "// two\nvar x = 1 + 2; // tail\n")
+	testFormat(t, "fun f() {\n    print g(1,\n        // arg\n        2);\n}\n", "fun f() {\n    // arg\n    print g(1, 2);\n}\n")
+	testFormat(t, "while a and\n// cond\nb {\nx;\n}\n", "// cond\nwhile a and b {\n    x;\n}\n")
+}
+
+func TestFormatBlankLines(t *testing.T) {
+	testFormat(t, "x;\n\n\n\ny;\n", "x;\n\ny;\n")
+	testFormat(t, "{\n\nx;\n\n}\n", "{\n    x;\n}\n")
+	testFormat(t, "\n\n// c\n\nx;", "// c\n\nx;\n")
+}
+
+func TestFormatParseError(t *testing.T) {
+	if _, err := Source("var x = ;"); err == nil {
+		t.Fatalf("Expected error formatting invalid program")
+	}
+}
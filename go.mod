@@ -1,9 +1,13 @@
 module golox
 
-go 1.13
+go 1.18
 
 require (
 	github.com/fatih/color v1.13.0
+	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5
+)
+
+require (
 	github.com/mattn/go-colorable v0.1.12 // indirect
-	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
 )
@@ -0,0 +1,130 @@
+//go:build integration
+// +build integration
+
+package interp
+
+import (
+	"bytes"
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Expectation comments, in the format of the Crafting Interpreters test suite:
+//
+//	print 1;  // expect: 1.000000
+//	print x;  // expect runtime error: Variable "x" does not exist in this scope.
+//	var = 1;  // Error: message
+//	// [line 3] Error: message
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectSyntaxError  = regexp.MustCompile(`// (\[line (\d+)\] )?(Error.*)`)
+)
+
+// Expectations of a test file
+type expectations struct {
+	output       []string
+	runtimeError string   // as "[line N] message", counted from 1
+	errors       []string // syntax errors as "[line N] Error: message", counted from 1
+}
+
+func parseExpectations(src string) expectations {
+	var exp expectations
+	for i, line := range strings.Split(src, "\n") {
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			exp.output = append(exp.output, m[1])
+		} else if m := expectRuntimeError.FindStringSubmatch(line); m != nil {
+			exp.runtimeError = fmt.Sprintf("[line %d] %s", i+1, m[1])
+		} else if m := expectSyntaxError.FindStringSubmatch(line); m != nil {
+			lineNum := i + 1
+			if m[2] != "" {
+				lineNum, _ = strconv.Atoi(m[2])
+			}
+			exp.errors = append(exp.errors, fmt.Sprintf("[line %d] %s", lineNum, m[3]))
+		}
+	}
+	return exp
+}
+
+// Runs src, returning what it printed, its syntax errors and its runtime error
+func runSource(src string) (output []string, errors []string, runtimeError string) {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	for _, e := range l.Errors() {
+		errors = append(errors, fmt.Sprintf("[line %d] Error: %s", e.Token.Line+1, e.Msg))
+	}
+	for _, e := range p.Errors() {
+		errors = append(errors, fmt.Sprintf("[line %d] Error: %s", e.Token.Line+1, e))
+	}
+	if len(errors) > 0 {
+		return nil, errors, ""
+	}
+
+	var stdout bytes.Buffer
+	// errors without a position are reported at the last statement started
+	line := 0
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if rerr, isRuntime := r.(*RuntimeError); isRuntime {
+					runtimeError = fmt.Sprintf("[line %d] %s", rerr.Token.Line+1, rerr.Msg)
+				} else {
+					runtimeError = fmt.Sprintf("[line %d] %v", line+1, r)
+				}
+			}
+		}()
+		intp := New()
+		intp.Stdout = &stdout
+		intp.OnStmt = func(_ *Interpreter, stmt ast.Stmt) {
+			line = ast.StmtToken(stmt).Line
+		}
+		intp.Eval(prog)
+	}()
+	out := strings.TrimSuffix(stdout.String(), "\n")
+	if out != "" {
+		output = strings.Split(out, "\n")
+	}
+	return output, nil, runtimeError
+}
+
+func assertLines(t *testing.T, what string, got, want []string) {
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %s:\n%s\ngot:\n%s", what, strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+// Runs every .lox file under testdata, checking it against its expectation comments
+func TestConformance(t *testing.T) {
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".lox" {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(path), "testdata/"), ".lox")
+		t.Run(name, func(t *testing.T) {
+			exp := parseExpectations(string(src))
+			output, errors, runtimeError := runSource(string(src))
+			assertLines(t, "syntax errors", errors, exp.errors)
+			assertLines(t, "output", output, exp.output)
+			if runtimeError != exp.runtimeError {
+				t.Errorf("Expected runtime error %q, got %q", exp.runtimeError, runtimeError)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
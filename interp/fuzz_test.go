@@ -0,0 +1,52 @@
+//go:build unit
+// +build unit
+
+package interp
+
+import (
+	"golox/lexer"
+	"golox/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func FuzzEval(f *testing.F) {
+	paths, _ := filepath.Glob("../examples/*.lox")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(src))
+	}
+	f.Add("return;")
+	f.Add("fun f() { return; } print f();")
+	f.Add("var x = 1; x();")
+	f.Add("var i = 0; while (i < 10) {}")
+	f.Fuzz(func(t *testing.T, src string) {
+		l := lexer.NewLexer(src)
+		p := parser.New(&l)
+		prog := p.ParseProgram()
+		if len(l.Errors()) > 0 || len(p.Errors()) > 0 {
+			return
+		}
+		intp := New()
+		intp.Stdout = io.Discard
+		// fuzzed programs often loop forever
+		intp.MaxSteps = 100000
+		defer func() {
+			// runtime errors of Lox programs are fine, bugs in the interpreter are not
+			switch r := recover().(type) {
+			case runtime.Error:
+				t.Fatalf("Go runtime error evaluating %q: %s", src, r)
+			case nil, string, *RuntimeError, *LimitError:
+			default:
+				t.Fatalf("Unexpected panic evaluating %q: %#v", src, r)
+			}
+		}()
+		intp.Eval(prog)
+	})
+}
@@ -4,9 +4,18 @@
 package interp
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"golox/lexer"
+	"golox/obj"
 	"golox/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Integration Test
@@ -155,3 +164,327 @@ func TestRecursion(t *testing.T) {
 	program := p.ParseProgram()
 	testExprNum(t, program, 4181)
 }
+
+func TestBuiltinCall(t *testing.T) {
+	l := lexer.NewLexer(`return twice(4) + 1;`)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	intp := New()
+	intp.EnvStack[0].Bind("twice", &obj.Builtin{Name: "twice", Arity: 1, Fn: func(args []obj.Obj) obj.Obj {
+		return &obj.Num{Value: 2 * args[0].(*obj.Num).Value}
+	}})
+	if val := intp.Eval(program); val.String() != "9.000000" {
+		t.Fatalf("Expected 9, got %s", val)
+	}
+}
+
+func TestBuiltinError(t *testing.T) {
+	l := lexer.NewLexer(`var x = 1;
+x = broken();`)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	intp := New()
+	intp.EnvStack[0].Bind("broken", &obj.Builtin{Name: "broken", Arity: 0, Fn: func(args []obj.Obj) obj.Obj {
+		panic("Broken.")
+	}})
+	defer func() {
+		err, isRuntime := recover().(*RuntimeError)
+		if !isRuntime || err.Token.Line != 1 || err.Token.LineOffset != 4 || err.Msg != "Broken." {
+			t.Fatalf("Expected a runtime error at the call, got %v", err)
+		}
+	}()
+	intp.Eval(program)
+}
+
+func TestStreams(t *testing.T) {
+	l := lexer.NewLexer(`var greeting = "hi";
+fun greet(n) { print greeting; print n; }
+greet(read());`)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	intp := New()
+	var stdout strings.Builder
+	intp.Stdin = strings.NewReader("2")
+	intp.Stdout = &stdout
+	// builtins doing I/O use the streams of the interpreter calling them
+	intp.EnvStack[0].Bind("read", &obj.Builtin{Name: "read", Arity: 0, Fn: func(args []obj.Obj) obj.Obj {
+		var n float64
+		fmt.Fscan(intp.Stdin, &n)
+		return &obj.Num{Value: n}
+	}})
+	intp.Eval(program)
+	if stdout.String() != "hi\n2.000000\n" {
+		t.Fatalf("Expected the greeting to be printed to Stdout, got %q", stdout.String())
+	}
+	stdout.Reset()
+	intp.PrintEnv()
+	if !strings.Contains(stdout.String(), "greeting") {
+		t.Fatalf("Expected the environment to be printed to Stdout, got %q", stdout.String())
+	}
+}
+
+// Evaluates src, returning the LimitError it stopped with
+func evalLimited(t *testing.T, intp *Interpreter, ctx context.Context, src string) (err *LimitError) {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	defer func() {
+		r := recover()
+		var isLimit bool
+		if err, isLimit = r.(*LimitError); !isLimit {
+			t.Fatalf("Expected a limit error, got %v", r)
+		}
+	}()
+	intp.EvalContext(ctx, program)
+	return nil
+}
+
+func TestMaxSteps(t *testing.T) {
+	intp := New()
+	intp.MaxSteps = 1000
+	err := evalLimited(t, &intp, context.Background(), `var i = 0;
+fun spin() { while true { i = i + 1; } }
+spin();`)
+	if !errors.Is(err, ErrMaxSteps) || err.Msg != "Evaluation exceeded its step budget." {
+		t.Fatalf("Expected the step budget to be exhausted, got %v", err)
+	}
+	// positioned at the statement being evaluated inside the call
+	if err.Token.Line != 1 {
+		t.Fatalf("Expected the error on line 1, got %d", err.Token.Line)
+	}
+	if intp.Steps() != 1001 {
+		t.Fatalf("Expected 1001 steps, got %d", intp.Steps())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	intp := New()
+	intp.EnvStack[0].Bind("stop", &obj.Builtin{Name: "stop", Arity: 0, Fn: func(args []obj.Obj) obj.Obj {
+		cancel()
+		return &obj.Nil{}
+	}})
+	err := evalLimited(t, &intp, ctx, `stop();
+while true {}`)
+	if !errors.Is(err, context.Canceled) || err.Msg != "Evaluation was cancelled." {
+		t.Fatalf("Expected evaluation to be cancelled, got %v", err)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	intp := New()
+	err := evalLimited(t, &intp, ctx, `while true {}`)
+	if !errors.Is(err, context.DeadlineExceeded) || err.Msg != "Evaluation timed out." {
+		t.Fatalf("Expected evaluation to time out, got %v", err)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	intp := New()
+	intp.MaxDepth = 100
+	err := evalLimited(t, &intp, context.Background(), `fun down(n) {
+    return down(n + 1);
+}
+down(0);`)
+	if !errors.Is(err, ErrStackOverflow) || err.Token.Line != 1 || err.Token.Lexeme != "down" {
+		t.Fatalf("Expected a stack overflow at the recursive call, got %v", err)
+	}
+	lines := strings.Split(err.Msg, "\n")
+	expected := []string{"Stack overflow: more than 100 nested calls.", "#0 down at line 2", "... 91 more frames ...", "#100 <script> at line 4"}
+	if len(lines) != 12 || lines[0] != expected[0] || lines[1] != expected[1] || lines[6] != expected[2] || lines[11] != expected[3] {
+		t.Fatalf("Expected the message to show the call stack, got:\n%s", err.Msg)
+	}
+	// the call stack is unwound
+	if len(intp.CallStack()) != 0 {
+		t.Fatalf("Expected an empty call stack, got %d frames", len(intp.CallStack()))
+	}
+	if New().MaxDepth != DefaultMaxDepth {
+		t.Fatalf("Expected new interpreters to have the default max depth")
+	}
+}
+
+func TestMaxAlloc(t *testing.T) {
+	intp := New()
+	intp.MaxAlloc = 10000
+	intp.EnvStack[0].Bind("big", &obj.Builtin{Name: "big", Arity: 0, Fn: func(args []obj.Obj) obj.Obj {
+		return &obj.Str{Value: strings.Repeat("x", 20000)}
+	}})
+	err := evalLimited(t, &intp, context.Background(), `var x = 1;
+var s = big();`)
+	if !errors.Is(err, ErrMaxAlloc) || err.Token.Line != 1 || err.Msg != "Evaluation exceeded its allocation budget." {
+		t.Fatalf("Expected the allocation budget to be exhausted on line 1, got %v", err)
+	}
+	if intp.Allocated() <= 20000 {
+		t.Fatalf("Expected the string to be counted, got %d bytes allocated", intp.Allocated())
+	}
+}
+
+func TestParallel(t *testing.T) {
+	src := `fun count(n) {
+    var i = 0;
+    while i < n { i = i + 1; }
+    return i;
+}
+print twice(count(100));`
+	// a program parsed once and a prelude are shared by every interpreter
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	shared := p.ParseProgram()
+	prelude := obj.NewEnv()
+	prelude.Bind("twice", &obj.Builtin{Name: "twice", Arity: 1, Fn: func(args []obj.Obj) obj.Obj {
+		return &obj.Num{Value: 2 * args[0].(*obj.Num).Value}
+	}})
+	var wg sync.WaitGroup
+	outputs := make([]strings.Builder, 16)
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			program := shared
+			// and others lex and parse their own
+			if i%2 == 0 {
+				l := lexer.NewLexer(src)
+				p := parser.New(&l)
+				program = p.ParseProgram()
+			}
+			intp := New()
+			intp.Prelude = prelude
+			intp.Stdout = &outputs[i]
+			intp.MaxSteps = 100000
+			intp.EvalContext(context.Background(), program)
+		}(i)
+	}
+	wg.Wait()
+	for i := range outputs {
+		if outputs[i].String() != "200.000000\n" {
+			t.Errorf("Expected interpreter %d to print 200, got %q", i, outputs[i].String())
+		}
+	}
+}
+
+// Writes files, by path relative to dir, and returns the path of the first
+func writeFiles(t *testing.T, dir string, files ...string) string {
+	for i := 0; i < len(files); i += 2 {
+		path := filepath.Join(dir, filepath.FromSlash(files[i]))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(files[0]))
+}
+
+// Evaluates the file at path, returning what it printed and the message of its runtime error, if any
+func evalFile(t *testing.T, intp *Interpreter, path string) (out string, msg string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := lexer.NewLexer(string(src))
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	var stdout strings.Builder
+	intp.File, intp.Stdout = path, &stdout
+	defer func() {
+		out = stdout.String()
+		switch r := recover().(type) {
+		case nil:
+		case *RuntimeError:
+			msg = r.Msg
+		case string:
+			msg = r
+		default:
+			t.Fatalf("Expected a runtime error, got %v", r)
+		}
+	}()
+	intp.Eval(program)
+	return
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	main := writeFiles(t, dir,
+		"main.lox", `import "lib/math.lox";
+import "lib/math.lox" as again;
+import "greet.lox";
+print math.square(3);
+print greet.hello(math.pi);
+print math == again;
+print math;`,
+		"lib/math.lox", `print "loading math";
+var pi = 3;
+fun square(x) { return x * x; }`,
+		// found in the search path, importing relative to itself
+		"shared/greet.lox", `import "../lib/math.lox" as m;
+fun hello(n) { return m.square(n) + 1; }`)
+	intp := New()
+	intp.ImportPath = []string{filepath.Join(dir, "shared")}
+	out, msg := evalFile(t, &intp, main)
+	expected := "loading math\n9.000000\n10.000000\ntrue\n<module math.lox>\n"
+	if out != expected || msg != "" {
+		t.Fatalf("Expected the output %q, got %q and error %q", expected, out, msg)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"a.lox", `import "b.lox";`,
+		"b.lox", `import "a.lox";`,
+		"self.lox", `import "self.lox";`,
+		"bad.lox", `var x 1;`,
+		"fails.lox", `var x = 1;
+print missing;`,
+		"math.lox", `var pi = 3;`)
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		// each import on the way says where it failed
+		{`import "a.lox";`, `In module "a.lox": [line 0:0] In module "b.lox": [line 0:0] Import cycle: a.lox imports b.lox imports a.lox.`},
+		{`import "self.lox";`, `In module "self.lox": [line 0:0] Import cycle: self.lox imports self.lox.`},
+		{`import "missing.lox";`, `Cannot find module "missing.lox".`},
+		{`import "bad.lox";`, "Cannot import \"bad.lox\", which has syntax errors:\n[line 0:6] Expected next token to be EQUAL, got NUMBER instead"},
+		{`import "fails.lox";`, `In module "fails.lox": Variable "missing" does not exist in this scope.`},
+		{`import "math.lox"; print math.e;`, `Module "math.lox" has no global "e".`},
+		{`import "math.lox"; math.pi = 4;`, `Cannot assign to "pi" of module "math.lox" outside of it.`},
+		{`import "math.lox"; import "math.lox";`, `Variable "math" already exists in this scope. Use "math = ...;" to assign instead.`},
+	}
+	// cycles are shown relative to the working directory
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		intp := New()
+		if _, msg := evalFile(t, &intp, writeFiles(t, dir, "main.lox", tt.src)); msg != tt.expected {
+			t.Errorf("Expected %s to fail with %q, got %q", tt.src, tt.expected, msg)
+		}
+	}
+	intp := New()
+	intp.NoImports = true
+	if _, msg := evalFile(t, &intp, writeFiles(t, dir, "main.lox", `import "math.lox";`)); msg != "Importing modules is not allowed." {
+		t.Errorf("Expected imports to be denied, got %q", msg)
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "spin.lox", `while true {}`)
+	intp := New()
+	intp.MaxSteps = 1000
+	intp.File = filepath.Join(dir, "main.lox")
+	// modules share the budgets of the program importing them
+	err := evalLimited(t, &intp, context.Background(), `import "spin.lox";`)
+	if !errors.Is(err, ErrMaxSteps) {
+		t.Fatalf("Expected the step budget to be exhausted in the module, got %v", err)
+	}
+}
@@ -1,31 +1,178 @@
 package interp
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"golox/ast"
 	"golox/obj"
 	"golox/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
 )
 
 type Interpreter struct {
 	EnvStack []obj.Env
+	// Prelude binds names, such as builtins, that programs see unless
+	// an environment of EnvStack binds them too. Programs cannot assign them.
+	Prelude obj.Env
+	// OnStmt is called with the innermost interpreter before each statement is evaluated
+	OnStmt func(intp *Interpreter, stmt ast.Stmt)
+	// Tracer, if set, is notified of everything the interpreter does
+	Tracer Tracer
+	// Streams of the program, which default to those of the process.
+	// print writes to Stdout, and builtins doing I/O use them too.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// MaxSteps, if positive, is how many nodes may be evaluated in total,
+	// as counted by Steps, before evaluation stops with a *LimitError
+	MaxSteps int
+	// MaxDepth, if positive, is how many calls may be nested
+	// before evaluation stops with a stack overflow *LimitError
+	MaxDepth int
+	// MaxAlloc, if positive, is about how many bytes of values and environments
+	// may be allocated in total, as counted by Allocated,
+	// before evaluation stops with a *LimitError
+	MaxAlloc int
+	// File is the path of the program being evaluated, which it imports modules relative to,
+	// or "" to import them relative to the working directory
+	File string
+	// ImportPath lists the directories searched for modules not found relative to File
+	ImportPath []string
+	// NoImports makes importing a module a runtime error, for hosts not letting programs read files
+	NoImports bool
+	// AllowImport, if set, is called with the absolute path of every file a module could be
+	// before it is looked at, and the files it returns an error for are treated as missing
+	AllowImport func(path string) error
+	frames      *[]*Frame       // call stack shared with the interpreters of every call
+	usage       *usage          // shared with the interpreters of every call
+	modules     *modules        // shared with the interpreters of every call and module
+	ctx         context.Context // stops evaluation when done, if set
+}
+
+// DefaultMaxDepth is the MaxDepth of new interpreters,
+// well below the depth at which Go's own stack would overflow
+const DefaultMaxDepth = 10000
+
+// What evaluation has used of its budgets
+type usage struct {
+	steps     int // nodes evaluated
+	allocated int // approximate bytes allocated
+}
+
+// RuntimeError is a runtime error at a position in the source
+type RuntimeError struct {
+	Token token.Token // where the error occurred
+	Msg   string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d:%d] %s", e.Token.Line, e.Token.LineOffset, e.Msg)
+}
+
+// Causes of a LimitError stopping a program that used up its MaxSteps, MaxDepth or MaxAlloc
+var (
+	ErrMaxSteps      = errors.New("step budget exhausted")
+	ErrStackOverflow = errors.New("stack overflow")
+	ErrMaxAlloc      = errors.New("allocation budget exhausted")
+)
+
+// LimitError is a runtime error stopping a program that was cancelled,
+// ran past its deadline or used up one of its budgets
+type LimitError struct {
+	RuntimeError
+	Err error // context.Canceled, context.DeadlineExceeded, ErrMaxSteps, ErrStackOverflow or ErrMaxAlloc
+}
+
+func (e *LimitError) Unwrap() error { return e.Err }
+
+// Frame is a function call, or the top level of a program, being evaluated
+type Frame struct {
+	Name string       // function name, or "<script>" for the top level
+	Call token.Token  // token of the call site, zero for the top level
+	Intp *Interpreter // interpreter evaluating the function body
+	Stmt ast.Stmt     // statement currently being evaluated
 }
 
 func New() Interpreter {
 	baseEnv := obj.NewEnv()
-	return Interpreter{EnvStack: []obj.Env{baseEnv}}
+	return Interpreter{EnvStack: []obj.Env{baseEnv}, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr,
+		MaxDepth: DefaultMaxDepth, frames: &[]*Frame{}, usage: &usage{}, modules: &modules{cache: make(map[string]*Module)}}
 }
+
+// CallStack returns the frames being evaluated, innermost first
+func (intp *Interpreter) CallStack() []*Frame {
+	if intp.frames == nil {
+		return nil
+	}
+	n := len(*intp.frames)
+	stack := make([]*Frame, n)
+	for i, f := range *intp.frames {
+		stack[n-1-i] = f
+	}
+	return stack
+}
+
+// Pushes a frame onto the shared call stack
+func (intp *Interpreter) pushFrame(f *Frame) {
+	if intp.frames == nil {
+		intp.frames = &[]*Frame{}
+	}
+	*intp.frames = append(*intp.frames, f)
+}
+
+// Pops the innermost frame off the shared call stack
+func (intp *Interpreter) popFrame() {
+	*intp.frames = (*intp.frames)[:len(*intp.frames)-1]
+}
+
+// PrintEnv writes the bindings of every scope to Stdout, innermost first
 func (intp *Interpreter) PrintEnv() {
 	i := len(intp.EnvStack) - 1
 	for i >= 0 {
-		fmt.Println("-----")
-		intp.EnvStack[i].PrintColored()
+		fmt.Fprintln(intp.Stdout, "-----")
+		intp.EnvStack[i].PrintColored(intp.Stdout)
 		i--
 	}
 }
 
+// Names returns every name bound in any scope of the environment stack, sorted
+func (intp *Interpreter) Names() []string {
+	seen := make(map[string]struct{})
+	var names []string
+	for _, env := range append([]obj.Env{intp.Prelude}, intp.EnvStack...) {
+		for name := range env.Bindings {
+			if _, dup := seen[name]; !dup {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the value of the innermost variable with the name, if there is one
+func (intp *Interpreter) Lookup(name string) (obj.Obj, bool) {
+	for i := len(intp.EnvStack) - 1; i >= 0; i-- {
+		if box, found := intp.EnvStack[i].Bindings[name]; found {
+			return *box.Ref, true
+		}
+	}
+	if box, found := intp.Prelude.Bindings[name]; found {
+		return *box.Ref, true
+	}
+	return nil, false
+}
+
 func (intp *Interpreter) bind(name string, val obj.Obj) {
 	intp.EnvStack[len(intp.EnvStack)-1].Bind(name, val)
+	if intp.Tracer != nil {
+		intp.Tracer.Bind(name, val)
+	}
 }
 func (intp *Interpreter) assign(name string, val obj.Obj) {
 	i := len(intp.EnvStack) - 1
@@ -33,10 +180,16 @@ func (intp *Interpreter) assign(name string, val obj.Obj) {
 		_, ok := intp.EnvStack[i].Bindings[name]
 		if ok {
 			intp.EnvStack[i].Bindings[name].Ref = &val
+			if intp.Tracer != nil {
+				intp.Tracer.Assign(name, val)
+			}
 			return
 		}
 		i--
 	}
+	if _, found := intp.Prelude.Bindings[name]; found {
+		panic(fmt.Sprintf("Cannot assign to builtin %q. Use \"var %s = ...;\" to declare a variable instead.", name, name))
+	}
 	panic(fmt.Sprintf("Attempted usage of variable %q which does not exist in this scope. Use \"var %s = ...;\" to declare instead.", name, name))
 }
 
@@ -49,23 +202,210 @@ func (intp *Interpreter) resolve(name *string) obj.Obj {
 		}
 		i--
 	}
+	if val, found := intp.Prelude.Bindings[*name]; found {
+		return *val.Ref
+	}
 	panic(fmt.Sprintf("Variable %q does not exist in this scope.", *name))
 }
 
+// Returns an interpreter for a function call with the same hooks as intp
+func (intp *Interpreter) child(envStack []obj.Env) *Interpreter {
+	return &Interpreter{EnvStack: envStack, Prelude: intp.Prelude, OnStmt: intp.OnStmt, Tracer: intp.Tracer,
+		Stdin: intp.Stdin, Stdout: intp.Stdout, Stderr: intp.Stderr, MaxSteps: intp.MaxSteps,
+		MaxDepth: intp.MaxDepth, MaxAlloc: intp.MaxAlloc, File: intp.File, ImportPath: intp.ImportPath,
+		NoImports: intp.NoImports, AllowImport: intp.AllowImport, frames: intp.frames, usage: intp.usage, modules: intp.modules, ctx: intp.ctx}
+}
+
+// Steps returns how many nodes have been evaluated by the interpreter and its calls
+func (intp *Interpreter) Steps() int {
+	if intp.usage == nil {
+		return 0
+	}
+	return intp.usage.steps
+}
+
+// Allocated returns about how many bytes of values and environments
+// the interpreter and its calls have allocated
+func (intp *Interpreter) Allocated() int {
+	if intp.usage == nil {
+		return 0
+	}
+	return intp.usage.allocated
+}
+
+// EvalContext evaluates node like Eval, stopping with a *LimitError once ctx is done
+func (intp *Interpreter) EvalContext(ctx context.Context, node ast.Node) obj.Obj {
+	outer := intp.ctx
+	intp.ctx = ctx
+	defer func() { intp.ctx = outer }()
+	return intp.Eval(node)
+}
+
+// Context returns the context given to EvalContext or CallContext while evaluating,
+// for builtins that wait to stop early, or context.Background()
+func (intp *Interpreter) Context() context.Context {
+	if intp.ctx == nil {
+		return context.Background()
+	}
+	return intp.ctx
+}
+
+// Counts a step, stopping evaluation if it is over the budget or cancelled
+func (intp *Interpreter) step() {
+	if intp.usage == nil {
+		intp.usage = &usage{}
+	}
+	intp.usage.steps++
+	if intp.MaxSteps > 0 && intp.usage.steps > intp.MaxSteps {
+		intp.stop(ErrMaxSteps, "Evaluation exceeded its step budget.")
+	}
+	if intp.ctx == nil {
+		return
+	}
+	select {
+	case <-intp.ctx.Done():
+		switch err := intp.ctx.Err(); err {
+		case context.Canceled:
+			intp.stop(err, "Evaluation was cancelled.")
+		case context.DeadlineExceeded:
+			intp.stop(err, "Evaluation timed out.")
+		default:
+			intp.stop(err, fmt.Sprintf("Evaluation was stopped: %s.", err))
+		}
+	default:
+	}
+}
+
+// Panics with a LimitError at the statement being evaluated
+func (intp *Interpreter) stop(err error, msg string) {
+	var tok token.Token
+	if frames := intp.CallStack(); len(frames) > 0 && frames[0].Stmt != nil {
+		tok = ast.StmtToken(frames[0].Stmt)
+	}
+	panic(&LimitError{RuntimeError: RuntimeError{Token: tok, Msg: msg}, Err: err})
+}
+
+// Counts bytes allocated, stopping evaluation if it is over the budget
+func (intp *Interpreter) alloc(bytes int) {
+	if intp.usage == nil {
+		intp.usage = &usage{}
+	}
+	intp.usage.allocated += bytes
+	if intp.MaxAlloc > 0 && intp.usage.allocated > intp.MaxAlloc {
+		intp.stop(ErrMaxAlloc, "Evaluation exceeded its allocation budget.")
+	}
+}
+
+// Rough sizes in bytes of what evaluation allocates
+const (
+	valueSize   = 16
+	envSize     = 48
+	bindingSize = 16
+)
+
+// Returns about how many bytes the value takes
+func sizeOf(val obj.Obj) int {
+	if s, isStr := val.(*obj.Str); isStr {
+		return valueSize + len(s.Value)
+	}
+	return valueSize
+}
+
+// Panics with a stack overflow LimitError if a call at call would nest too deep,
+// showing the innermost and outermost frames of the call stack
+func (intp *Interpreter) checkDepth(call token.Token) {
+	if intp.MaxDepth <= 0 || intp.frames == nil || len(*intp.frames) < intp.MaxDepth {
+		return
+	}
+	frames := intp.CallStack()
+	calls := 0
+	for _, f := range frames {
+		// the top level of the program is not a call
+		if f.Name != "<script>" {
+			calls++
+		}
+	}
+	if calls < intp.MaxDepth {
+		return
+	}
+	const shown = 5
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "Stack overflow: more than %d nested calls.", intp.MaxDepth)
+	for i, f := range frames {
+		if i == shown && len(frames) > 2*shown {
+			fmt.Fprintf(&msg, "\n... %d more frames ...", len(frames)-2*shown)
+		}
+		if i >= shown && i < len(frames)-shown {
+			continue
+		}
+		line := 0
+		if f.Stmt != nil {
+			line = ast.StmtToken(f.Stmt).Line
+		}
+		fmt.Fprintf(&msg, "\n#%d %s at line %d", i, f.Name, line+1)
+	}
+	panic(&LimitError{RuntimeError: RuntimeError{Token: call, Msg: msg.String()}, Err: ErrStackOverflow})
+}
+
 func (intp *Interpreter) Eval(node ast.Node) obj.Obj {
+	intp.step()
+	var val obj.Obj
+	if intp.Tracer != nil {
+		val = intp.traceEval(node)
+	} else {
+		val = intp.eval(node)
+	}
+	if _, isExpr := node.(ast.Expr); isExpr {
+		intp.alloc(sizeOf(val))
+	}
+	return val
+}
+
+// Evaluates node, notifying the tracer
+func (intp *Interpreter) traceEval(node ast.Node) obj.Obj {
+	intp.Tracer.Enter(node)
+	defer func() {
+		if r := recover(); r != nil {
+			intp.Tracer.Error(node, r)
+			panic(r)
+		}
+	}()
+	val := intp.eval(node)
+	intp.Tracer.Exit(node, val)
+	return val
+}
+
+func (intp *Interpreter) eval(node ast.Node) obj.Obj {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
+		intp.pushFrame(&Frame{Name: "<script>", Intp: intp})
+		defer intp.popFrame()
 		return intp.evalStmts(node.Statements, false)
 	case *ast.ExprStmt:
 		return intp.Eval(node.Expr)
 	case *ast.ReturnStmt:
+		if node.ReturnValue == nil {
+			return &obj.RetVal{Val: &obj.Nil{}}
+		}
 		return &obj.RetVal{Val: intp.Eval(node.ReturnValue)}
+	case *ast.DebuggerStmt:
+		// an attached debugger has already stopped before it through OnStmt
+		return nil
+	case *ast.ImportStmt:
+		intp.evalImport(node)
+		return nil
 	case *ast.BlockStmt:
 		return intp.evalBlock(node, true)
 	case *ast.AssignStmt:
 		intp.assign(node.Name.String(), intp.Eval(node.Expr))
 		return nil
+	case *ast.SetStmt:
+		object := intp.object(node.Object, node.Name.Token)
+		val := intp.Eval(node.Value)
+		defer positionErrors(node.Name.Token)
+		object.Set(node.Name.String(), val)
+		return nil
 	case *ast.WhileStmt:
 		// TODO: work out what should be truthy here
 		for isTruthy(intp.Eval(node.Cond)) {
@@ -79,12 +419,13 @@ func (intp *Interpreter) Eval(node ast.Node) obj.Obj {
 	case *ast.FuncDeclStmt:
 		closEnvStack := make([]obj.Env, len(intp.EnvStack))
 		for i, env := range intp.EnvStack {
+			intp.alloc(envSize + bindingSize*len(env.Bindings))
 			closEnvStack[i] = obj.NewEnv()
 			for k, v := range env.Bindings {
 				closEnvStack[i].Bindings[k] = v
 			}
 		}
-		closure := &obj.Closure{EnvStack: closEnvStack, Params: node.Params, Body: node.Body}
+		closure := &obj.Closure{EnvStack: closEnvStack, Params: node.Params, Body: node.Body, Decl: node}
 		intp.bind(node.Name.String(), closure)
 		return nil
 	case *ast.VarStmt:
@@ -93,7 +434,7 @@ func (intp *Interpreter) Eval(node ast.Node) obj.Obj {
 		return nil
 	case *ast.PrintStmt:
 		val := intp.Eval(node.Expr)
-		fmt.Println(val)
+		fmt.Fprintln(intp.Stdout, val)
 		return nil
 	case *ast.IfStmt:
 		cond := intp.Eval(node.Cond)
@@ -124,30 +465,149 @@ func (intp *Interpreter) Eval(node ast.Node) obj.Obj {
 	case *ast.InfixExpr:
 		return intp.evalInfix(node)
 	case *ast.CallExpr:
-		name := &node.Token.Lexeme
-		o := intp.resolve(name)
-		closure, isClos := o.(*obj.Closure)
-		if !isClos {
-			panic(fmt.Sprintf("Unable to call variable %q (of type %T) as a function.", *name, o))
-		}
-		if len(node.Args) != len(closure.Params) {
-			panic(fmt.Sprintf("Function %q expects %d arguments, got %d instead", *name, len(closure.Params), len(node.Args)))
+		return intp.callExpr(node.Token, intp.resolve(&node.Token.Lexeme), node.Args)
+	case *ast.GetExpr:
+		object := intp.object(node.Object, node.Name.Token)
+		defer positionErrors(node.Name.Token)
+		return object.Get(node.Name.String())
+	case *ast.MethodCallExpr:
+		object := intp.object(node.Object, node.Name.Token)
+		return intp.callExpr(node.Token, intp.get(object, node.Name.Token), node.Args)
+	}
+	panic(fmt.Sprintf("Unable to evaluate unexpected expression, got: %T", node))
+}
+
+// Evaluates the object whose property is named by the name token
+func (intp *Interpreter) object(expr ast.Expr, name token.Token) obj.Object {
+	val := intp.Eval(expr)
+	object, isObject := val.(obj.Object)
+	if !isObject {
+		panic(&RuntimeError{Token: name, Msg: fmt.Sprintf("Only objects have properties, got %s.", val.Type())})
+	}
+	return object
+}
+
+// Returns the property of an object named by the name token
+func (intp *Interpreter) get(object obj.Object, name token.Token) obj.Obj {
+	defer positionErrors(name)
+	return object.Get(name.Lexeme)
+}
+
+// Calls the function fn with the arguments of a call expression
+func (intp *Interpreter) callExpr(call token.Token, fn obj.Obj, args []ast.Expr) obj.Obj {
+	switch fn := fn.(type) {
+	case *obj.Closure:
+		return intp.callClosure(call, fn, intp.evalArgs(call, args, len(fn.Params)))
+	case *obj.Builtin:
+		return intp.callBuiltin(call, fn, intp.evalArgs(call, args, fn.Arity))
+	default:
+		panic(fmt.Sprintf("Unable to call variable %q (of type %T) as a function.", call.Lexeme, fn))
+	}
+}
+
+// Evaluates the arguments of a call, checking there are arity of them unless it is negative
+func (intp *Interpreter) evalArgs(call token.Token, argExprs []ast.Expr, arity int) []obj.Obj {
+	if arity >= 0 && len(argExprs) != arity {
+		msg := fmt.Sprintf("Function %q expects %d arguments, got %d instead", call.Lexeme, arity, len(argExprs))
+		panic(&RuntimeError{Token: call, Msg: msg})
+	}
+	args := make([]obj.Obj, len(argExprs))
+	for i, arg := range argExprs {
+		args[i] = intp.Eval(arg)
+	}
+	return args
+}
+
+// Positions the runtime error being panicked with at tok, if it has no position.
+// It must be deferred.
+func positionErrors(tok token.Token) {
+	if r := recover(); r != nil {
+		if msg, isMsg := r.(string); isMsg {
+			panic(&RuntimeError{Token: tok, Msg: msg})
 		}
-		localCallEnv := obj.NewEnv()
-		for i, arg := range node.Args {
-			val := intp.Eval(arg)
-			localCallEnv.Bind(closure.Params[i].String(), val)
+		panic(r)
+	}
+}
+
+// Call calls a function value with already evaluated arguments,
+// as if it was called by the name in the call token
+func (intp *Interpreter) Call(call token.Token, fn obj.Obj, args []obj.Obj) obj.Obj {
+	arity := 0
+	switch fn := fn.(type) {
+	case *obj.Closure:
+		arity = len(fn.Params)
+	case *obj.Builtin:
+		arity = fn.Arity
+	default:
+		panic(fmt.Sprintf("Unable to call variable %q (of type %T) as a function.", call.Lexeme, fn))
+	}
+	if arity >= 0 && len(args) != arity {
+		panic(fmt.Sprintf("Function %q expects %d arguments, got %d instead", call.Lexeme, arity, len(args)))
+	}
+	if closure, isClosure := fn.(*obj.Closure); isClosure {
+		return intp.callClosure(call, closure, args)
+	}
+	return intp.callBuiltin(call, fn.(*obj.Builtin), args)
+}
+
+// CallContext calls fn like Call, stopping with a *LimitError once ctx is done
+func (intp *Interpreter) CallContext(ctx context.Context, call token.Token, fn obj.Obj, args []obj.Obj) obj.Obj {
+	outer := intp.ctx
+	intp.ctx = ctx
+	defer func() { intp.ctx = outer }()
+	return intp.Call(call, fn, args)
+}
+
+func (intp *Interpreter) callClosure(call token.Token, closure *obj.Closure, args []obj.Obj) obj.Obj {
+	intp.checkDepth(call)
+	name := call.Lexeme
+	// every Call is followed by its Return, even if the call fails before its body runs
+	var ret obj.Obj
+	if intp.Tracer != nil {
+		intp.Tracer.Call(name, call, args)
+		defer func() { intp.Tracer.Return(name, ret) }()
+	}
+	intp.alloc(envSize + bindingSize*(len(args)+1))
+	localCallEnv := obj.NewEnv()
+	for i, val := range args {
+		localCallEnv.Bind(closure.Params[i].String(), val)
+		if intp.Tracer != nil {
+			intp.Tracer.Bind(closure.Params[i].String(), val)
 		}
-		localCallEnv.Bind(node.Token.Lexeme, closure)
-		funcEnvStack := append(closure.EnvStack, localCallEnv)
-		funcIntp := Interpreter{EnvStack: funcEnvStack}
-		ret := funcIntp.evalBlock(closure.Body, false)
-		return ret
 	}
-	panic(fmt.Sprintf("Unable to evaluate unexpected expression, got: %T", node))
+	localCallEnv.Bind(name, closure)
+	funcEnvStack := append(closure.EnvStack, localCallEnv)
+	funcIntp := intp.child(funcEnvStack)
+	funcIntp.pushFrame(&Frame{Name: name, Call: call, Intp: funcIntp})
+	defer funcIntp.popFrame()
+	ret = funcIntp.evalBlock(closure.Body, false)
+	return ret
+}
+
+// Calls a builtin, positioning the runtime errors it reports at the call
+func (intp *Interpreter) callBuiltin(call token.Token, fn *obj.Builtin, args []obj.Obj) obj.Obj {
+	name := call.Lexeme
+	if intp.Tracer != nil {
+		intp.Tracer.Call(name, call, args)
+	}
+	var ret obj.Obj
+	if intp.Tracer != nil {
+		defer func() { intp.Tracer.Return(name, ret) }()
+	}
+	defer positionErrors(call)
+	if fn.FnContext != nil {
+		ret = fn.FnContext(intp.Context(), args)
+	} else {
+		ret = fn.Fn(args)
+	}
+	if ret == nil {
+		ret = &obj.Nil{}
+	}
+	return ret
 }
 
 func (intp *Interpreter) evalBlock(bs *ast.BlockStmt, bubbleReturn bool) obj.Obj {
+	intp.alloc(envSize)
 	newEnv := obj.NewEnv()
 	intp.EnvStack = append(intp.EnvStack, newEnv)
 	defer func() { intp.EnvStack = intp.EnvStack[:len(intp.EnvStack)-1] }()
@@ -158,6 +618,12 @@ func (intp *Interpreter) evalBlock(bs *ast.BlockStmt, bubbleReturn bool) obj.Obj
 func (intp *Interpreter) evalStmts(stmts []ast.Stmt, bubbleReturn bool) obj.Obj {
 	var result obj.Obj
 	for _, stmt := range stmts {
+		if intp.frames != nil && len(*intp.frames) > 0 {
+			(*intp.frames)[len(*intp.frames)-1].Stmt = stmt
+		}
+		if intp.OnStmt != nil {
+			intp.OnStmt(intp, stmt)
+		}
 		result = intp.Eval(stmt)
 		retVal, isRetVal := result.(*obj.RetVal)
 		if isRetVal {
@@ -260,10 +726,22 @@ func isEq(a obj.Obj, b obj.Obj) bool {
 			return false
 		}
 		return true
+	case obj.Object:
+		return a.Equal(b)
 	}
 	panic(fmt.Sprintf("Unable to compare objects. Got: %T and %T", a, b))
 }
 
+// IsEqual returns if two values are equal with ==
+func IsEqual(a obj.Obj, b obj.Obj) bool {
+	return isEq(a, b)
+}
+
+// IsTruthy returns if a value counts as true in a condition
+func IsTruthy(o obj.Obj) bool {
+	return isTruthy(o)
+}
+
 func isTruthy(o obj.Obj) bool {
 	// TODO: resolve variables to value
 	switch o := o.(type) {
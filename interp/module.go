@@ -0,0 +1,204 @@
+package interp
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is the namespace of an imported file, whose properties are its globals
+type Module struct {
+	Path string  // absolute path of the file
+	Env  obj.Env // globals declared by the file
+}
+
+func (m *Module) Type() obj.ObjType { return obj.OBJECT_OBJ }
+func (m *Module) String() string    { return "<module " + filepath.Base(m.Path) + ">" }
+
+// Get returns the global of the module with the name
+func (m *Module) Get(name string) obj.Obj {
+	box, found := m.Env.Bindings[name]
+	if !found {
+		panic(fmt.Sprintf("Module %q has no global %q.", filepath.Base(m.Path), name))
+	}
+	return *box.Ref
+}
+
+// Set panics, as the globals of modules can only be assigned by the module itself
+func (m *Module) Set(name string, val obj.Obj) {
+	panic(fmt.Sprintf("Cannot assign to %q of module %q outside of it.", name, filepath.Base(m.Path)))
+}
+
+// Properties returns the names of the module's globals, sorted
+func (m *Module) Properties() []string {
+	names := make([]string, 0, len(m.Env.Bindings))
+	for name := range m.Env.Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Equal returns if other is the same module, which every import of a file shares
+func (m *Module) Equal(other obj.Obj) bool {
+	o, isModule := other.(*Module)
+	return isModule && o == m
+}
+
+// Modules imported while evaluating a program
+type modules struct {
+	cache   map[string]*Module // by absolute path
+	loading []string           // absolute paths of the files being imported, outermost first
+}
+
+// Evaluates the module an import statement names, unless it was already imported,
+// and declares it in the current scope
+func (intp *Interpreter) evalImport(stmt *ast.ImportStmt) {
+	if intp.NoImports {
+		panic(&RuntimeError{Token: stmt.Token, Msg: "Importing modules is not allowed."})
+	}
+	if intp.modules == nil {
+		intp.modules = &modules{cache: make(map[string]*Module)}
+	}
+	path := intp.findModule(stmt)
+	mod, found := intp.modules.cache[path]
+	if !found {
+		mod = intp.loadModule(stmt, path)
+		intp.modules.cache[path] = mod
+	}
+	intp.bind(stmt.Name().String(), mod)
+}
+
+// Returns the absolute path of the file an import statement names: relative to
+// the directory of the importing file, or else to one of the ImportPath directories.
+// Files that AllowImport denies are not looked at, and are reported as missing,
+// so that programs cannot find out whether files they may not read exist.
+func (intp *Interpreter) findModule(stmt *ast.ImportStmt) string {
+	path := filepath.FromSlash(stmt.ModulePath())
+	dirs := []string{"."}
+	if intp.File != "" {
+		dirs[0] = filepath.Dir(intp.File)
+	}
+	if filepath.IsAbs(path) {
+		dirs = []string{""}
+	} else {
+		dirs = append(dirs, intp.ImportPath...)
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, path)
+		if intp.AllowImport != nil {
+			abs, err := filepath.Abs(candidate)
+			if err != nil || intp.AllowImport(abs) != nil {
+				continue
+			}
+		}
+		if info, err := os.Stat(candidate); err != nil || info.IsDir() {
+			continue
+		}
+		if abs, err := realPath(candidate); err == nil {
+			return abs
+		}
+	}
+	msg := fmt.Sprintf("Cannot find module %s", stmt.Path.Lexeme)
+	if len(dirs) > 1 {
+		msg += " in " + strings.Join(dirs, ", ")
+	}
+	panic(&RuntimeError{Token: stmt.Path, Msg: msg + "."})
+}
+
+// Lexes, parses and evaluates the module at path in its own global environment,
+// sharing the budgets and context of intp but not its OnStmt and Tracer hooks,
+// which are for the importing file
+func (intp *Interpreter) loadModule(stmt *ast.ImportStmt, path string) *Module {
+	importing := intp.modules.loading
+	if len(importing) == 0 && intp.File != "" {
+		if abs, err := realPath(intp.File); err == nil {
+			importing = []string{abs}
+		}
+	}
+	for i, p := range importing {
+		if p == path {
+			cycle := make([]string, 0, len(importing)-i+1)
+			for _, p := range append(importing[i:], path) {
+				cycle = append(cycle, displayPath(p))
+			}
+			panic(&RuntimeError{Token: stmt.Token, Msg: fmt.Sprintf("Import cycle: %s.", strings.Join(cycle, " imports "))})
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		panic(&RuntimeError{Token: stmt.Token, Msg: fmt.Sprintf("Cannot import %s: %s.", stmt.Path.Lexeme, err)})
+	}
+	l := lexer.NewLexer(string(src))
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	var errs []string
+	for _, e := range l.Errors() {
+		errs = append(errs, e.Error())
+	}
+	for _, e := range p.Errors() {
+		errs = append(errs, fmt.Sprintf("[line %d:%d] %s", e.Token.Line, e.Token.LineOffset, e))
+	}
+	if len(errs) > 0 {
+		msg := fmt.Sprintf("Cannot import %s, which has syntax errors:\n%s", stmt.Path.Lexeme, strings.Join(errs, "\n"))
+		panic(&RuntimeError{Token: stmt.Token, Msg: msg})
+	}
+
+	outer := intp.modules.loading
+	intp.modules.loading = append(append([]string{}, importing...), path)
+	defer func() { intp.modules.loading = outer }()
+	modIntp := intp.child([]obj.Env{obj.NewEnv()})
+	modIntp.File, modIntp.OnStmt, modIntp.Tracer = path, nil, nil
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panic(moduleError(stmt, r))
+			}
+		}()
+		modIntp.Eval(prog)
+	}()
+	return &Module{Path: path, Env: modIntp.EnvStack[0]}
+}
+
+// Returns the runtime error of an import statement whose module panicked with r,
+// keeping the position of the error in the module
+func moduleError(stmt *ast.ImportStmt, r interface{}) interface{} {
+	switch err := r.(type) {
+	case *LimitError:
+		// the whole program stops, not just the import
+		return err
+	case *RuntimeError:
+		return &RuntimeError{Token: stmt.Token, Msg: fmt.Sprintf("In module %s: %s", stmt.Path.Lexeme, err)}
+	}
+	return &RuntimeError{Token: stmt.Token, Msg: fmt.Sprintf("In module %s: %v", stmt.Path.Lexeme, r)}
+}
+
+// Returns the absolute path of a file with its symbolic links resolved,
+// which is the same for every path to it
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// Returns path relative to the working directory if it is under it, to keep messages short
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,125 @@
+//go:build integration
+// +build integration
+
+package interp
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"golox/token"
+	"strings"
+	"testing"
+)
+
+// Records tracer events as strings
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) Enter(node ast.Node) {
+	if _, isStmt := node.(ast.Stmt); isStmt {
+		r.events = append(r.events, "enter "+node.String())
+	}
+}
+func (r *recorder) Exit(node ast.Node, val obj.Obj) {
+	if _, isStmt := node.(ast.Stmt); isStmt {
+		r.events = append(r.events, "exit "+node.String())
+	}
+}
+func (r *recorder) Error(node ast.Node, err interface{}) {
+	r.events = append(r.events, fmt.Sprintf("error %s: %v", node, err))
+}
+func (r *recorder) Call(name string, call token.Token, args []obj.Obj) {
+	r.events = append(r.events, fmt.Sprintf("call %s %v", name, args))
+}
+func (r *recorder) Return(name string, val obj.Obj) {
+	r.events = append(r.events, fmt.Sprintf("return %s %v", name, val))
+}
+func (r *recorder) Bind(name string, val obj.Obj) {
+	r.events = append(r.events, fmt.Sprintf("bind %s %v", name, val))
+}
+func (r *recorder) Assign(name string, val obj.Obj) {
+	r.events = append(r.events, fmt.Sprintf("assign %s %v", name, val))
+}
+
+func traceProgram(t *testing.T, input string) (events []string) {
+	l := lexer.NewLexer(input)
+	p := parser.New(&l)
+	program := p.ParseProgram()
+	intp := New()
+	rec := &recorder{}
+	intp.Tracer = rec
+	defer func() {
+		recover()
+		events = rec.events
+	}()
+	intp.Eval(program)
+	return rec.events
+}
+
+func assertEvents(t *testing.T, got []string, want ...string) {
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Expected events:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestTracer(t *testing.T) {
+	events := traceProgram(t, `fun id(x) { return x; }
+var a = id(1);
+a = 2;`)
+	assertEvents(t, events,
+		"enter fun id(x) {return x;}",
+		"bind id fun(x) {return x;}",
+		"exit fun id(x) {return x;}",
+		"enter var a = id(1);",
+		"call id [1.000000]",
+		"bind x 1.000000",
+		"enter return x;",
+		"exit return x;",
+		"return id 1.000000",
+		"bind a 1.000000",
+		"exit var a = id(1);",
+		"enter a = 2;",
+		"assign a 2.000000",
+		"exit a = 2;",
+	)
+}
+
+func TestTracerError(t *testing.T) {
+	events := traceProgram(t, `fun f() { return nope; }
+f();`)
+	assertEvents(t, events,
+		"enter fun f() {return nope;}",
+		"bind f fun() {return nope;}",
+		"exit fun f() {return nope;}",
+		"enter f()",
+		"call f []",
+		"enter return nope;",
+		`error nope: Variable "nope" does not exist in this scope.`,
+		`error return nope;: Variable "nope" does not exist in this scope.`,
+		"return f <nil>",
+		`error f(): Variable "nope" does not exist in this scope.`,
+		`error f(): Variable "nope" does not exist in this scope.`,
+		`error fun f() {return nope;}f(): Variable "nope" does not exist in this scope.`,
+	)
+}
+
+func TestTracerCallFailing(t *testing.T) {
+	// the call fails binding its argument, before its body runs
+	events := traceProgram(t, `fun f(f) {}
+f(1);`)
+	calls, returns := 0, 0
+	for _, e := range events {
+		if strings.HasPrefix(e, "call ") {
+			calls++
+		} else if strings.HasPrefix(e, "return ") {
+			returns++
+		}
+	}
+	if calls != 1 || returns != 1 {
+		t.Fatalf("Expected one call and its return, got:\n%s", strings.Join(events, "\n"))
+	}
+}
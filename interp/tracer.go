@@ -0,0 +1,70 @@
+package interp
+
+import (
+	"golox/ast"
+	"golox/obj"
+	"golox/token"
+)
+
+// Tracer observes evaluation, to follow control flow or build tools such as profilers
+type Tracer interface {
+	// Enter is called before a node is evaluated
+	Enter(node ast.Node)
+	// Exit is called after a node is evaluated, with its value
+	Exit(node ast.Node, val obj.Obj)
+	// Error is called instead of Exit for each node being evaluated
+	// when a runtime error occurs, innermost first
+	Error(node ast.Node, err interface{})
+	// Call is called when a function is called, once its arguments are evaluated
+	Call(name string, call token.Token, args []obj.Obj)
+	// Return is called when a function call ends, with its return value,
+	// or nil if it ended with a runtime error
+	Return(name string, val obj.Obj)
+	// Bind is called when a variable is declared
+	Bind(name string, val obj.Obj)
+	// Assign is called when a variable is assigned
+	Assign(name string, val obj.Obj)
+}
+
+// MultiTracer returns a Tracer notifying each of tracers in turn
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) Enter(node ast.Node) {
+	for _, t := range m {
+		t.Enter(node)
+	}
+}
+func (m multiTracer) Exit(node ast.Node, val obj.Obj) {
+	for _, t := range m {
+		t.Exit(node, val)
+	}
+}
+func (m multiTracer) Error(node ast.Node, err interface{}) {
+	for _, t := range m {
+		t.Error(node, err)
+	}
+}
+func (m multiTracer) Call(name string, call token.Token, args []obj.Obj) {
+	for _, t := range m {
+		t.Call(name, call, args)
+	}
+}
+func (m multiTracer) Return(name string, val obj.Obj) {
+	for _, t := range m {
+		t.Return(name, val)
+	}
+}
+func (m multiTracer) Bind(name string, val obj.Obj) {
+	for _, t := range m {
+		t.Bind(name, val)
+	}
+}
+func (m multiTracer) Assign(name string, val obj.Obj) {
+	for _, t := range m {
+		t.Assign(name, val)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build unit
+// +build unit
+
+package lexer
+
+import (
+	"golox/token"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Adds the example programs to the seed corpus of f
+func addExamples(f *testing.F) {
+	paths, _ := filepath.Glob("../examples/*.lox")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(src))
+	}
+}
+
+func FuzzScanTokens(f *testing.F) {
+	addExamples(f)
+	f.Add(`"unterminated`)
+	f.Add("// comment\nvar x = 1.5;")
+	f.Fuzz(func(t *testing.T, src string) {
+		for _, keep := range []bool{false, true} {
+			l := NewLexer(src)
+			l.KeepComments = keep
+			toks := l.ScanTokens()
+			if len(toks) == 0 || toks[len(toks)-1].Type != token.EOF {
+				t.Fatalf("Expected the tokens to end with EOF, got %v", toks)
+			}
+			for _, tok := range toks[:len(toks)-1] {
+				if tok.Type == token.EOF {
+					t.Fatalf("Unexpected EOF before the end in %v", toks)
+				}
+			}
+		}
+	})
+}
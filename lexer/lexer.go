@@ -3,44 +3,72 @@ package lexer
 
 import (
 	"fmt"
-	"golox/report"
 	. "golox/token"
+	"sort"
 	"strconv"
 )
 
 // Keyword lookup table
 var keywords map[string]TokenType = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"class":    CLASS,
+	"debugger": DEBUGGER,
+	"else":     ELSE,
+	"false":    FALSE,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"true":     TRUE,
+	"var":      VAR,
+	"while":    WHILE,
+}
+
+// LexError is an error found while scanning the INVALID token Token
+type LexError struct {
+	Token Token
+	Msg   string
+}
+
+func (e LexError) Error() string {
+	return fmt.Sprintf("[line %d:%d] %s", e.Token.Line, e.Token.LineOffset, e.Msg)
 }
 
 // Lexer is used to tokenize source code
 type Lexer struct {
-	Source     string
-	lexStart   int
-	current    int
-	lineOffset int
-	line       int
-	lineStart  int
+	Source       string
+	KeepComments bool // emit COMMENT tokens instead of skipping comments
+	lexStart     int
+	lexLine      int // line the current token starts on
+	lexLineStart int // start of the line the current token starts on
+	current      int
+	lineOffset   int
+	line         int
+	lineStart    int
+	errors       []LexError
 }
 
 // NewLexer returns a new lexer scanner at the start of source
 func NewLexer(source string) Lexer {
-	return Lexer{source, 0, 0, 0, 0, 0}
+	return Lexer{Source: source}
+}
+
+// Errors returns the errors found so far, in the order they were scanned
+func (s *Lexer) Errors() []LexError {
+	return s.errors
+}
+
+// Make an INVALID token and record the error that caused it
+func (s *Lexer) newErrorToken(msg string) Token {
+	tok := s.newToken(INVALID)
+	s.errors = append(s.errors, LexError{tok, msg})
+	return tok
 }
 
 // Returns if lexer has reached the EOF
@@ -93,25 +121,26 @@ func (s *Lexer) match(expected byte) bool {
 // Make new token of a given TokenType with nil content
 func (s *Lexer) newToken(toktype TokenType) Token {
 	lex := s.Source[s.lexStart:s.current]
-	return NewToken(toktype, lex, s.line, s.lexStart-s.lineStart, nil)
+	return NewToken(toktype, lex, s.lexLine, s.lexStart-s.lexLineStart, nil)
 }
 
 // Make new token of a given TokenType with literal content
 func (s *Lexer) newTokenWithLiteral(toktype TokenType, val interface{}) Token {
 	lex := s.Source[s.lexStart:s.current]
-	return NewToken(toktype, lex, s.line, s.lexStart-s.lineStart, val)
+	return NewToken(toktype, lex, s.lexLine, s.lexStart-s.lexLineStart, val)
 }
 
 // ScanToken scans, consumes, and returns next Token
 func (s *Lexer) ScanToken() Token {
 	found := false
-loop:
 	for !found {
 		found = true
 		var res Token
 		s.skipWhitespace()
 
 		s.lexStart = s.current
+		s.lexLine = s.line
+		s.lexLineStart = s.lineStart
 
 		c := s.advance()
 		switch c {
@@ -135,6 +164,8 @@ loop:
 			res = s.newToken(SEMICOLON)
 		case '*':
 			res = s.newToken(STAR)
+		case ':':
+			res = s.newToken(COLON)
 		case '!':
 			var toktype TokenType
 			if s.match('=') {
@@ -173,7 +204,11 @@ loop:
 				for !s.isAtEnd() && s.peek() != '\n' {
 					s.current++
 				}
-				found = false
+				if s.KeepComments {
+					res = s.newToken(COMMENT)
+				} else {
+					found = false
+				}
 			} else {
 				res = s.newToken(SLASH)
 			}
@@ -188,8 +223,7 @@ loop:
 			} else if isDigit(c) {
 				res = s.takeNumber()
 			} else {
-				report.Error(s.line, s.lineOffset, fmt.Sprintf("Unexpected character: '%c'", c))
-				break loop
+				res = s.newErrorToken(fmt.Sprintf("Unexpected character: '%c'", c))
 			}
 		}
 		if found {
@@ -241,14 +275,16 @@ func (s *Lexer) takeIdentifier() Token {
 func (s *Lexer) takeString() Token {
 	for !s.isAtEnd() && s.peek() != '"' {
 		if s.peek() == '\n' {
+			s.advance()
 			s.line++
 			s.lineOffset = 0
+			s.lineStart = s.current
+			continue
 		}
 		s.advance()
 	}
 	if s.isAtEnd() {
-		report.Error(s.line, s.lineOffset, "Unterminated string.")
-		return s.newToken(INVALID)
+		return s.newErrorToken("Unterminated string.")
 	}
 	s.advance()
 	str := s.Source[s.lexStart+1 : s.current-1]
@@ -274,11 +310,13 @@ func (s *Lexer) takeNumber() Token {
 // ScanTokens scans and consumes all tokens until EOF and returns Token list
 func (s *Lexer) ScanTokens() []Token {
 	var tokens []Token
-	for !s.isAtEnd() {
-		tokens = append(tokens, s.ScanToken())
+	for {
+		tok := s.ScanToken()
+		tokens = append(tokens, tok)
+		if tok.Type == EOF {
+			return tokens
+		}
 	}
-	tokens = append(tokens, NewToken(EOF, "", s.line, s.lineOffset+1, nil))
-	return tokens
 }
 
 // Returns if char is 0-9 digit
@@ -295,3 +333,13 @@ func isAlpha(c byte) bool {
 func isAlphaNumeric(c byte) bool {
 	return isDigit(c) || isAlpha(c)
 }
+
+// Keywords returns every reserved word of the language in sorted order
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
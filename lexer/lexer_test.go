@@ -235,3 +235,54 @@ func TestKeywords(t *testing.T) {
 	}
 	testTokens(t, input, tests)
 }
+
+func TestKeepComments(t *testing.T) {
+	input := `1 // first
+    // second
+    2`
+	l := NewLexer(input)
+	l.KeepComments = true
+	tests := []struct {
+		expectedType       token.TokenType
+		expectedLexeme     string
+		expectedLine       int
+		expectedLineOffset int
+	}{
+		{token.NUMBER, "1", 0, 0},
+		{token.COMMENT, "// first", 0, 2},
+		{token.COMMENT, "// second", 1, 4},
+		{token.NUMBER, "2", 2, 4},
+		{token.EOF, "", 2, 5},
+	}
+	for i, tt := range tests {
+		tok := l.ScanToken()
+		if tok.Type != tt.expectedType || tok.Lexeme != tt.expectedLexeme {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLexeme, tok.Type, tok.Lexeme)
+		}
+		if tok.Line != tt.expectedLine || tok.LineOffset != tt.expectedLineOffset {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedLineOffset, tok.Line, tok.LineOffset)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	input := `x & y
+    "unclosed`
+	l := NewLexer(input)
+	toks := l.ScanTokens()
+	if len(toks) != 5 || toks[4].Type != token.EOF {
+		t.Fatalf("Expected 5 tokens ending in a single EOF, got: %v", toks)
+	}
+	errs := l.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Msg != "Unexpected character: '&'" || errs[0].Token.Line != 0 || errs[0].Token.LineOffset != 2 {
+		t.Fatalf("Wrong first error, got: %s", errs[0])
+	}
+	if errs[1].Msg != "Unterminated string." || errs[1].Token.Line != 1 || errs[1].Token.LineOffset != 4 {
+		t.Fatalf("Wrong second error, got: %s", errs[1])
+	}
+}
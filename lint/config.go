@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ConfigFile is the name of the file "golox lint" reads its config from by default
+const ConfigFile = ".loxlint.json"
+
+// Config selects the rules to run, and is read from JSON such as
+//
+//	{"disable": ["unused-variable"], "globals": ["clock"]}
+type Config struct {
+	Disable []string `json:"disable"` // IDs of rules not to run
+	Globals []string `json:"globals"` // names declared outside of programs, such as builtins
+}
+
+// LoadConfig reads the config in the JSON file at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return config, nil
+}
+
+// Validate returns an error if a rule disabled does not exist
+func (c *Config) Validate() error {
+	for _, id := range c.Disable {
+		if lookupRule(id) == nil {
+			return fmt.Errorf("unknown rule %q", id)
+		}
+	}
+	return nil
+}
+
+func (c *Config) enabled(id string) bool {
+	for _, d := range c.Disable {
+		if d == id {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+// Package lint reports likely mistakes in Lox programs that are otherwise
+// only found at runtime, if ever
+package lint
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"golox/scope"
+	"golox/token"
+	"sort"
+	"strings"
+)
+
+// Warning is a likely mistake found by a rule
+type Warning struct {
+	Rule string      // ID of the rule that found it
+	Pos  token.Token // token the warning is about
+	Msg  string
+}
+
+// String returns the warning as "line:column: message (rule)", counted from 1
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", w.Pos.Line+1, w.Pos.LineOffset+1, w.Msg, w.Rule)
+}
+
+// Rule is a check run over a whole program
+type Rule struct {
+	ID    string
+	Doc   string
+	check func(*pass)
+}
+
+// Rules holds every rule, in the order their warnings are reported for the same position
+var Rules = []*Rule{
+	{
+		ID:    "unused-variable",
+		Doc:   "a variable is declared but never read",
+		check: checkUnused,
+	},
+	{
+		ID:    "unused-import",
+		Doc:   "a module is imported but never read",
+		check: checkUnusedImports,
+	},
+	{
+		ID:    "unreachable-code",
+		Doc:   "a statement follows a return in the same block, so never runs",
+		check: checkUnreachable,
+	},
+	{
+		ID:    "param-shadows-global",
+		Doc:   "a parameter has the name of a global, which the function can then not use",
+		check: checkShadowedGlobals,
+	},
+	{
+		ID:    "assign-undeclared",
+		Doc:   "a name is assigned without ever being declared",
+		check: checkUndeclaredAssigns,
+	},
+}
+
+// Returns the rule with the ID, or nil
+func lookupRule(id string) *Rule {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// State of linting a program, shared by the rules
+type pass struct {
+	prog     *ast.Program
+	info     *scope.Info
+	config   *Config
+	assigns  map[token.Token]bool // name tokens of assignment statements
+	rule     *Rule
+	warnings []Warning
+}
+
+func (p *pass) report(pos token.Token, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, Warning{Rule: p.rule.ID, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Returns if a global with the name is declared anywhere in the program,
+// or outside of it according to the config.
+// Functions can use globals declared after them, as long as they are only called afterwards.
+func (p *pass) isGlobal(name string) bool {
+	for _, sym := range p.info.Global.Symbols {
+		if sym.Name == name {
+			return true
+		}
+	}
+	for _, g := range p.config.Globals {
+		if g == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Program runs the rules enabled in config over prog, and returns their warnings sorted by position.
+// comments are those of the program's source, whose lint:disable directives are obeyed.
+func Program(prog *ast.Program, comments []token.Token, config *Config) []Warning {
+	if config == nil {
+		config = &Config{}
+	}
+	p := &pass{prog: prog, info: scope.Resolve(prog), config: config, assigns: make(map[token.Token]bool)}
+	ast.Inspect(prog, func(n ast.Node) bool {
+		if assign, isAssign := n.(*ast.AssignStmt); isAssign {
+			p.assigns[assign.Name.Token] = true
+		}
+		return true
+	})
+	for _, rule := range Rules {
+		if !config.enabled(rule.ID) {
+			continue
+		}
+		p.rule = rule
+		rule.check(p)
+	}
+	warnings := disabledByComments(comments).filter(p.warnings)
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].Pos, warnings[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.LineOffset < b.LineOffset
+	})
+	return warnings
+}
+
+// Source parses src and lints it, returning the parse errors instead if there are any
+func Source(src string, config *Config) ([]Warning, []parser.ParserError) {
+	l := lexer.NewLexer(src)
+	l.KeepComments = true
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, p.Errors()
+	}
+	return Program(prog, p.Comments(), config), nil
+}
+
+// Variables whose every use is an assignment
+func checkUnused(p *pass) {
+	for _, sym := range p.info.Symbols {
+		if sym.Kind == scope.Var && !p.isRead(sym) {
+			p.report(sym.Decl, "Variable %q is declared but never used.", sym.Name)
+		}
+	}
+}
+
+// Modules whose every use is an assignment, reported at the import
+func checkUnusedImports(p *pass) {
+	for _, sym := range p.info.Symbols {
+		if sym.Kind == scope.Module && !p.isRead(sym) {
+			p.report(sym.Import.Token, "Module %q is imported but never used.", sym.Name)
+		}
+	}
+}
+
+// Returns if a symbol is read anywhere
+func (p *pass) isRead(sym *scope.Symbol) bool {
+	for _, ref := range sym.Refs {
+		if !p.assigns[ref] {
+			return true
+		}
+	}
+	// functions declared before a global can still read it
+	if sym.Scope == p.info.Global {
+		for _, tok := range p.info.Unresolved {
+			if tok.Lexeme == sym.Name && !p.assigns[tok] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Statements after one that always returns, reported once per block
+func checkUnreachable(p *pass) {
+	checkBlock := func(stmts []ast.Stmt) {
+		for i, stmt := range stmts {
+			if returns(stmt) && i+1 < len(stmts) {
+				p.report(ast.StmtToken(stmts[i+1]), "Unreachable code after return.")
+				return
+			}
+		}
+	}
+	checkBlock(p.prog.Statements)
+	ast.Inspect(p.prog, func(n ast.Node) bool {
+		if bs, isBlock := n.(*ast.BlockStmt); isBlock {
+			checkBlock(bs.Statements)
+		}
+		return true
+	})
+}
+
+// Returns if stmt always returns, so statements after it never run
+func returns(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt:
+		return stmt != nil
+	case *ast.BlockStmt:
+		return stmt != nil && blockReturns(stmt)
+	case *ast.IfStmt:
+		return stmt != nil && blockReturns(stmt.OnTrue) && blockReturns(stmt.OnFalse)
+	}
+	return false
+}
+
+func blockReturns(bs *ast.BlockStmt) bool {
+	if bs == nil {
+		return false
+	}
+	for _, stmt := range bs.Statements {
+		if returns(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkShadowedGlobals(p *pass) {
+	for _, sym := range p.info.Symbols {
+		if sym.Kind == scope.Param && p.isGlobal(sym.Name) {
+			p.report(sym.Decl, "Parameter %q shadows the global of the same name.", sym.Name)
+		}
+	}
+}
+
+// Assignments to names with no declaration a function could see when called
+func checkUndeclaredAssigns(p *pass) {
+	for _, tok := range p.info.Unresolved {
+		if p.assigns[tok] && !p.isGlobal(tok.Lexeme) {
+			p.report(tok, "Assignment to undeclared variable %q.", tok.Lexeme)
+		}
+	}
+}
+
+// Rules disabled by lint:disable comments, by line, with line -1 for the whole file
+// and rule "" for every rule
+type disabled map[int][]string
+
+// Returns the rules disabled by comments of the form
+//
+//	// lint:disable rule ...       for the comment's line and the line after
+//	// lint:disable-file rule ...  for the whole file
+//
+// where no rules means every rule
+func disabledByComments(comments []token.Token) disabled {
+	d := make(disabled)
+	for _, c := range comments {
+		fields := strings.Fields(strings.TrimPrefix(c.Lexeme, "//"))
+		if len(fields) == 0 {
+			continue
+		}
+		rules := fields[1:]
+		if len(rules) == 0 {
+			rules = []string{""}
+		}
+		switch fields[0] {
+		case "lint:disable":
+			d[c.Line] = append(d[c.Line], rules...)
+			d[c.Line+1] = append(d[c.Line+1], rules...)
+		case "lint:disable-file":
+			d[-1] = append(d[-1], rules...)
+		}
+	}
+	return d
+}
+
+func (d disabled) filter(warnings []Warning) []Warning {
+	kept := []Warning{}
+	for _, w := range warnings {
+		if !d.has(-1, w.Rule) && !d.has(w.Pos.Line, w.Rule) {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
+
+func (d disabled) has(line int, rule string) bool {
+	for _, r := range d[line] {
+		if r == "" || r == rule {
+			return true
+		}
+	}
+	return false
+}
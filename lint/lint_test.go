@@ -0,0 +1,136 @@
+//go:build unit
+// +build unit
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testLint(t *testing.T, source string, config *Config, expected ...string) {
+	t.Helper()
+	warnings, errors := Source(source, config)
+	if len(errors) > 0 {
+		t.Fatalf("Unexpected parse errors: %v", errors)
+	}
+	got := make([]string, len(warnings))
+	for i, w := range warnings {
+		got[i] = w.String()
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected warnings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestUnusedVariable(t *testing.T) {
+	testLint(t, `var a = 1;
+var b = 2;
+b = 3;
+var c = 4;
+print c;
+fun f() {
+    var d = 5;
+    return e;
+}
+var e = 6;`, nil,
+		`1:5: Variable "a" is declared but never used. (unused-variable)`,
+		`2:5: Variable "b" is declared but never used. (unused-variable)`,
+		`7:9: Variable "d" is declared but never used. (unused-variable)`,
+	)
+}
+
+func TestUnusedImport(t *testing.T) {
+	testLint(t, `import "lib/math.lox";
+import "lib/strings.lox" as str;
+import "lib/time.lox";
+print math.pi;
+fun now() {
+    return time.clock();
+}`, nil,
+		`2:1: Module "str" is imported but never used. (unused-import)`,
+	)
+}
+
+func TestUnreachableCode(t *testing.T) {
+	testLint(t, `fun f(x) {
+    if (x) {
+        return 1;
+        print x;
+        print x;
+    } else {
+        return 2;
+    }
+    print x;
+}
+fun g(x) {
+    if (x) {
+        return 1;
+    }
+    return 2;
+}
+print f(1) + g(1);`, nil,
+		`4:9: Unreachable code after return. (unreachable-code)`,
+		`9:5: Unreachable code after return. (unreachable-code)`,
+	)
+}
+
+func TestParamShadowsGlobal(t *testing.T) {
+	testLint(t, `var x = 1;
+fun f(x, y) {
+    return x + y;
+}
+print f(x, 2);`, &Config{Globals: []string{"y"}},
+		`2:7: Parameter "x" shadows the global of the same name. (param-shadows-global)`,
+		`2:10: Parameter "y" shadows the global of the same name. (param-shadows-global)`,
+	)
+}
+
+func TestAssignUndeclared(t *testing.T) {
+	testLint(t, `fun f() {
+    count = count + 1;
+    total = 0;
+    builtin = 1;
+}
+var count = 0;
+x = 1;`, &Config{Globals: []string{"builtin"}},
+		`3:5: Assignment to undeclared variable "total". (assign-undeclared)`,
+		`7:1: Assignment to undeclared variable "x". (assign-undeclared)`,
+	)
+}
+
+func TestDisable(t *testing.T) {
+	source := `// lint:disable-file assign-undeclared
+var a = 1; // lint:disable unused-variable
+// lint:disable
+var b = 2;
+var c = 3; // lint:disable unreachable-code
+x = 1;`
+	testLint(t, source, nil,
+		`5:5: Variable "c" is declared but never used. (unused-variable)`,
+	)
+	testLint(t, source, &Config{Disable: []string{"unused-variable"}})
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFile)
+	os.WriteFile(path, []byte(`{"disable": ["unused-variable"], "globals": ["clock"]}`), 0644)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.enabled("unused-variable") || !config.enabled("unreachable-code") {
+		t.Fatalf("Wrong rules enabled by %+v", config)
+	}
+	if len(config.Globals) != 1 || config.Globals[0] != "clock" {
+		t.Fatalf("Expected globals [clock], got %v", config.Globals)
+	}
+
+	os.WriteFile(path, []byte(`{"disable": ["no-such-rule"]}`), 0644)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), `unknown rule "no-such-rule"`) {
+		t.Fatalf("Expected an unknown rule error, got %v", err)
+	}
+}
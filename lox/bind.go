@@ -0,0 +1,214 @@
+package lox
+
+import (
+	"fmt"
+	"golox/obj"
+	"math"
+	"reflect"
+)
+
+var (
+	valueType = reflect.TypeOf(Value{})
+	objType   = reflect.TypeOf((*obj.Obj)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Func returns a Lox function named name that calls the Go function fn,
+// converting its arguments from Lox and its results back to Lox.
+//
+// Parameters can be strings, booleans, any integer or floating point type,
+// Value, obj.Obj, interface{}, which receives the argument's Interface(),
+// or pointers to structs, which are exposed to Lox as by Object.
+// A variadic fn takes any number of arguments.
+// fn can return nothing, one result of the same types, an error, or a result and an error.
+// A returned error, or an argument of the wrong type, stops the script
+// with a runtime error at the call.
+func Func(name string, fn interface{}) (Value, error) {
+	f := reflect.ValueOf(fn)
+	t := f.Type()
+	if t.Kind() != reflect.Func {
+		return Nil(), fmt.Errorf("cannot bind %q: expected a function, got %s", name, t)
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		in := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			in = in.Elem()
+		}
+		if !convertible(in) {
+			return Nil(), fmt.Errorf("cannot bind %q: unsupported parameter type %s", name, in)
+		}
+	}
+	results := t.NumOut()
+	if results > 0 && t.Out(results-1) == errorType {
+		results--
+	}
+	if results > 1 {
+		return Nil(), fmt.Errorf("cannot bind %q: expected at most one result besides an error, got %d", name, results)
+	}
+	if results == 1 && !convertible(t.Out(0)) {
+		return Nil(), fmt.Errorf("cannot bind %q: unsupported result type %s", name, t.Out(0))
+	}
+
+	arity := t.NumIn()
+	if t.IsVariadic() {
+		arity = -1
+	}
+	return Value{&obj.Builtin{Name: name, Arity: arity, Fn: func(args []obj.Obj) obj.Obj {
+		if t.IsVariadic() && len(args) < t.NumIn()-1 {
+			panic(fmt.Sprintf("Function %q expects at least %d arguments, got %d instead", name, t.NumIn()-1, len(args)))
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := t.In(min(i, t.NumIn()-1))
+			if t.IsVariadic() && i >= t.NumIn()-1 {
+				paramType = paramType.Elem()
+			}
+			v, err := toGo(arg, paramType)
+			if err != "" {
+				panic(fmt.Sprintf("Argument %d of %q must be %s.", i+1, name, err))
+			}
+			in[i] = v
+		}
+		out := f.Call(in)
+		if len(out) > results {
+			if err, _ := out[results].Interface().(error); err != nil {
+				panic(err.Error())
+			}
+		}
+		if results == 0 {
+			return &obj.Nil{}
+		}
+		return fromGo(out[0])
+	}}}, nil
+}
+
+// Register binds a global function named name that calls the Go function fn, as Func does
+func (l *Interpreter) Register(name string, fn interface{}) error {
+	v, err := Func(name, fn)
+	if err != nil {
+		return err
+	}
+	l.SetGlobal(name, v)
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Returns if values of the Go type can be converted to and from Lox
+func convertible(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0 || t == objType
+	}
+	return t == valueType || isStructPtr(t)
+}
+
+// Converts a Lox value to the Go type, or describes the value expected if it cannot
+func toGo(o obj.Obj, t reflect.Type) (reflect.Value, string) {
+	v := Value{o}
+	switch {
+	case t == valueType:
+		return reflect.ValueOf(v), ""
+	case t == objType:
+		return reflect.ValueOf(&o).Elem(), ""
+	case t.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(t), ""
+		}
+		return reflect.ValueOf(v.Interface()), ""
+	}
+	got := fmt.Sprintf("got %s", o.Type())
+	if isStructPtr(t) {
+		if object, isObject := o.(*object); isObject && object.ptr.Type() == t {
+			return object.ptr, ""
+		}
+		if v.IsNil() {
+			return reflect.Zero(t), ""
+		}
+		return reflect.Value{}, fmt.Sprintf("a %s object, %s", t.Elem().Name(), got)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, isBool := v.AsBool(); isBool {
+			return reflect.ValueOf(b).Convert(t), ""
+		}
+		return reflect.Value{}, "a bool, " + got
+	case reflect.String:
+		if s, isStr := v.AsString(); isStr {
+			return reflect.ValueOf(s).Convert(t), ""
+		}
+		return reflect.Value{}, "a string, " + got
+	}
+	n, isNum := v.AsNumber()
+	if !isNum {
+		return reflect.Value{}, "a number, " + got
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(n).Convert(t), ""
+	}
+	// integers must be whole numbers that fit in the type
+	if n != math.Trunc(n) {
+		return reflect.Value{}, fmt.Sprintf("a whole number, got %g", n)
+	}
+	i := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 || n >= math.Pow(2, float64(t.Bits())) {
+			return reflect.Value{}, fmt.Sprintf("a number that fits in %s, got %g", t, n)
+		}
+		i.SetUint(uint64(n))
+	default:
+		if n < -math.Pow(2, float64(t.Bits()-1)) || n >= math.Pow(2, float64(t.Bits()-1)) {
+			return reflect.Value{}, fmt.Sprintf("a number that fits in %s, got %g", t, n)
+		}
+		i.SetInt(int64(n))
+	}
+	return i, ""
+}
+
+// Converts a Go value of a convertible type to Lox
+func fromGo(v reflect.Value) obj.Obj {
+	switch v.Kind() {
+	case reflect.Bool:
+		return &obj.Bool{Value: v.Bool()}
+	case reflect.String:
+		return &obj.Str{Value: v.String()}
+	case reflect.Float32, reflect.Float64:
+		return &obj.Num{Value: v.Float()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &obj.Num{Value: float64(v.Int())}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &obj.Num{Value: float64(v.Uint())}
+	case reflect.Interface:
+		if v.IsNil() {
+			return &obj.Nil{}
+		}
+		return fromGo(v.Elem())
+	case reflect.Ptr:
+		// an obj.Obj held by an interface{}
+		if o, isObj := v.Interface().(obj.Obj); isObj {
+			return o
+		}
+		if isStructPtr(v.Type()) {
+			if v.IsNil() {
+				return &obj.Nil{}
+			}
+			// copy the pointer, which may be read from a field that is later reassigned
+			return &object{reflect.ValueOf(v.Interface())}
+		}
+	}
+	if v.Type() == valueType {
+		return v.Interface().(Value).Obj()
+	}
+	panic(fmt.Sprintf("Unable to convert %s to a Lox value.", v.Type()))
+}
@@ -0,0 +1,109 @@
+//go:build unit
+// +build unit
+
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	l := New(Options{})
+	funcs := map[string]interface{}{
+		"hasPrefix": func(s, prefix string) bool { return strings.HasPrefix(s, prefix) },
+		"repeat":    func(s string, n int) string { return strings.Repeat(s, n) },
+		"half":      func(n float32) float64 { return float64(n) / 2 },
+		"sum": func(ns ...float64) float64 {
+			total := 0.0
+			for _, n := range ns {
+				total += n
+			}
+			return total
+		},
+		"describe": func(v interface{}) string { return fmt.Sprintf("%T", v) },
+		"identity": func(v Value) Value { return v },
+		"nothing":  func() {},
+		"noResult": func() interface{} { return nil },
+		"check": func(ok bool) (uint8, error) {
+			if !ok {
+				return 0, errors.New("check failed")
+			}
+			return 1, nil
+		},
+	}
+	for name, fn := range funcs {
+		if err := l.Register(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]string{
+		`hasPrefix("golox", "go");`:  "true",
+		`repeat("ab", 3);`:           "ababab",
+		`half(3);`:                   "1.500000",
+		`sum();`:                     "0.000000",
+		`sum(1, 2, 3);`:              "6.000000",
+		`describe(1);`:               "float64",
+		`describe(nil);`:             "<nil>",
+		`describe(describe);`:        "*obj.Builtin",
+		`identity("x");`:             "x",
+		`nothing();`:                 "nil",
+		`noResult();`:                "nil",
+		`check(true);`:               "1.000000",
+		`var r = repeat; r("-", 2);`: "--",
+	}
+	for src, expected := range tests {
+		v, err := l.Eval(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if v.String() != expected {
+			t.Errorf("%s: expected %s, got %s", src, expected, v)
+		}
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	l := New(Options{})
+	l.Register("repeat", func(s string, n int) string { return strings.Repeat(s, n) })
+	l.Register("first", func(n uint8, rest ...string) {})
+	l.Register("fail", func() error { return errors.New("disk full") })
+	tests := map[string]string{
+		"\n  repeat(1, 2);":   `2:3: Argument 1 of "repeat" must be a string, got number.`,
+		`repeat("a", 1.5);`:   `1:1: Argument 2 of "repeat" must be a whole number, got 1.5.`,
+		`repeat("a");`:        `1:1: Function "repeat" expects 2 arguments, got 1 instead`,
+		`print repeat("a");`:  `1:7: Function "repeat" expects 2 arguments, got 1 instead`,
+		`first(256);`:         `1:1: Argument 1 of "first" must be a number that fits in uint8, got 256.`,
+		`first();`:            `1:1: Function "first" expects at least 1 arguments, got 0 instead`,
+		`first(1, "a", nil);`: `1:1: Argument 3 of "first" must be a string, got nil.`,
+		`fail();`:             `1:1: disk full`,
+	}
+	for src, expected := range tests {
+		_, err := l.Eval(src)
+		var rerr *RuntimeError
+		if !errors.As(err, &rerr) || err.Error() != expected {
+			t.Errorf("%s: expected runtime error %q, got %v", src, expected, err)
+		}
+	}
+}
+
+func TestRegisterUnsupported(t *testing.T) {
+	l := New(Options{})
+	unsupported := map[string]interface{}{
+		"notFunc":   42,
+		"slice":     func([]int) {},
+		"results":   func() (int, int) { return 0, 0 },
+		"resultMap": func() map[string]int { return nil },
+	}
+	for name, fn := range unsupported {
+		if err := l.Register(name, fn); err == nil {
+			t.Errorf("Expected %s not to be bound", name)
+		}
+		if _, found := l.GetGlobal(name); found {
+			t.Errorf("Expected %s not to be a global", name)
+		}
+	}
+}
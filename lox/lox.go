@@ -0,0 +1,318 @@
+// Package lox embeds the Lox interpreter in Go programs.
+//
+//	l := lox.New(lox.Options{})
+//	l.SetGlobal("limit", lox.Number(10))
+//	if _, err := l.Eval(`fun double(n) { return n * 2; }`); err != nil {
+//		return err
+//	}
+//	v, err := l.Call("double", lox.Number(21))
+//
+// Programs keep their globals between calls to Eval, as in the REPL,
+// and Save and Restore carry them over to another Interpreter.
+// Errors are returned as a *SyntaxError or a *RuntimeError, and never panic.
+//
+// Untrusted programs can be stopped with EvalContext and CallContext,
+// or limited to a number of steps with Options.MaxSteps:
+//
+//	ctx, cancel := context.WithTimeout(ctx, time.Second)
+//	defer cancel()
+//	_, err := l.EvalContext(ctx, src)
+//	if errors.Is(err, context.DeadlineExceeded) {
+//
+// Separate Interpreters can run in parallel, sharing their builtins through a Prelude.
+package lox
+
+import (
+	"context"
+	"fmt"
+	"golox/ast"
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"golox/session"
+	"golox/stdlib"
+	"golox/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Options configure an Interpreter
+type Options struct {
+	Globals map[string]Value // bound before any program runs
+	Tracer  interp.Tracer    // notified of everything the interpreter does, if set
+	// Permissions grant programs groups of builtin functions, such as stdlib.Read.
+	// The builtins of the other groups fail with a permission error.
+	// If nil, no builtins are declared.
+	Permissions stdlib.Permissions
+	// Prelude, if set, declares the builtins instead of Permissions,
+	// without copying them for each Interpreter
+	Prelude *Prelude
+	// Streams of the programs, instead of those of the process if set.
+	// print writes to Stdout.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// MaxSteps, if positive, is how many nodes each call to Eval or Call may evaluate
+	// before stopping with a RuntimeError wrapping interp.ErrMaxSteps
+	MaxSteps int
+	// MaxDepth, if positive, replaces interp.DefaultMaxDepth as how many calls may be nested
+	// before stopping with a RuntimeError wrapping interp.ErrStackOverflow
+	MaxDepth int
+	// MaxAlloc, if positive, is about how many bytes each call to Eval or Call may allocate
+	// before stopping with a RuntimeError wrapping interp.ErrMaxAlloc
+	MaxAlloc int
+	// Imports lets programs import modules, reading their files.
+	// Modules are found relative to the file run by EvalFile, or to the working directory,
+	// and else under the directories of ImportPath.
+	Imports    bool
+	ImportPath []string
+}
+
+// Interpreter runs Lox programs sharing the same global environment.
+// It must not be used by several goroutines at once,
+// but separate Interpreters can run in parallel.
+type Interpreter struct {
+	intp     interp.Interpreter
+	last     *token.Token // first token of the statement being evaluated, if any
+	maxSteps int
+	maxAlloc int
+}
+
+// New returns an Interpreter with the globals in opts
+func New(opts Options) *Interpreter {
+	l := &Interpreter{intp: interp.New(), maxSteps: opts.MaxSteps, maxAlloc: opts.MaxAlloc}
+	if opts.MaxDepth > 0 {
+		l.intp.MaxDepth = opts.MaxDepth
+	}
+	l.intp.Tracer = opts.Tracer
+	l.intp.NoImports, l.intp.ImportPath = !opts.Imports, opts.ImportPath
+	if opts.Stdin != nil {
+		l.intp.Stdin = opts.Stdin
+	}
+	if opts.Stdout != nil {
+		l.intp.Stdout = opts.Stdout
+	}
+	if opts.Stderr != nil {
+		l.intp.Stderr = opts.Stderr
+	}
+	l.intp.OnStmt = func(_ *interp.Interpreter, stmt ast.Stmt) {
+		tok := ast.StmtToken(stmt)
+		l.last = &tok
+	}
+	if opts.Prelude != nil {
+		l.intp.Prelude = opts.Prelude.env
+	} else if opts.Permissions != nil {
+		stdlib.Install(&l.intp, opts.Permissions)
+	}
+	for name, v := range opts.Globals {
+		l.SetGlobal(name, v)
+	}
+	return l
+}
+
+// Prelude is a read-only set of builtins that Interpreters, even running in parallel, can share
+type Prelude struct {
+	env obj.Env
+}
+
+// NewPrelude returns a Prelude with the builtins perms grants, as Options.Permissions does,
+// and the values in builtins, such as functions from Func.
+// Programs can set the properties of objects, which are then not safe to share.
+func NewPrelude(perms stdlib.Permissions, builtins map[string]Value) *Prelude {
+	p := &Prelude{stdlib.Prelude(perms)}
+	for name, v := range builtins {
+		if box, found := p.env.Bindings[name]; found {
+			o := v.Obj()
+			box.Ref = &o
+			continue
+		}
+		p.env.Bind(name, v.Obj())
+	}
+	return p
+}
+
+// Error is an error at a position in a Lox program
+type Error struct {
+	Line   int // counted from 1, or 0 if unknown
+	Column int // counted from 1, or 0 if unknown
+	Msg    string
+	Err    error // the limit that stopped the program, such as context.DeadlineExceeded, if any
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+func errorAt(tok token.Token, msg string) *Error {
+	return &Error{Line: tok.Line + 1, Column: tok.LineOffset + 1, Msg: msg}
+}
+
+// SyntaxError holds every error found while lexing and parsing a program,
+// which is then not run
+type SyntaxError struct {
+	Errors []*Error
+}
+
+func (e *SyntaxError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// RuntimeError is an error that stopped a program. Errors reported without a position
+// are positioned at the start of the statement being evaluated.
+type RuntimeError Error
+
+func (e *RuntimeError) Error() string {
+	return (*Error)(e).Error()
+}
+
+// Unwrap returns the cause of a program stopped by its context or a budget,
+// such as context.DeadlineExceeded or interp.ErrStackOverflow
+func (e *RuntimeError) Unwrap() error { return e.Err }
+
+// Eval runs src and returns the value of its last statement if it is an expression,
+// or the value of a top-level return, or nil
+func (l *Interpreter) Eval(src string) (Value, error) {
+	return l.EvalContext(context.Background(), src)
+}
+
+// EvalContext runs src as Eval, stopping it with a RuntimeError wrapping
+// the context's error once ctx is done
+func (l *Interpreter) EvalContext(ctx context.Context, src string) (Value, error) {
+	lex := lexer.NewLexer(src)
+	p := parser.New(&lex)
+	prog := p.ParseProgram()
+	if len(lex.Errors()) > 0 || len(p.Errors()) > 0 {
+		syntaxErr := &SyntaxError{}
+		for _, e := range lex.Errors() {
+			syntaxErr.Errors = append(syntaxErr.Errors, errorAt(e.Token, e.Msg))
+		}
+		for _, e := range p.Errors() {
+			syntaxErr.Errors = append(syntaxErr.Errors, errorAt(e.Token, e.Error()))
+		}
+		return Nil(), syntaxErr
+	}
+	return l.run(func() Value { return FromObj(l.intp.EvalContext(ctx, prog)) })
+}
+
+// EvalFile runs the program in the file at path, as Eval,
+// importing modules relative to it
+func (l *Interpreter) EvalFile(path string) (Value, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Nil(), err
+	}
+	outer := l.intp.File
+	l.intp.File = path
+	defer func() { l.intp.File = outer }()
+	return l.Eval(string(src))
+}
+
+// Call calls the global function named name with args
+func (l *Interpreter) Call(name string, args ...Value) (Value, error) {
+	return l.CallContext(context.Background(), name, args...)
+}
+
+// CallContext calls the function as Call, stopping it as EvalContext does
+func (l *Interpreter) CallContext(ctx context.Context, name string, args ...Value) (Value, error) {
+	fn, found := l.intp.Lookup(name)
+	if !found {
+		return Nil(), &RuntimeError{Msg: fmt.Sprintf("Function %q does not exist.", name)}
+	}
+	objs := make([]obj.Obj, len(args))
+	for i, arg := range args {
+		objs[i] = arg.Obj()
+	}
+	call := token.Token{Type: token.IDENTIFIER, Lexeme: name}
+	return l.run(func() Value { return FromObj(l.intp.CallContext(ctx, call, fn, objs)) })
+}
+
+// Runs f, turning the runtime errors it panics with into errors
+func (l *Interpreter) run(f func() Value) (val Value, err error) {
+	l.last = nil
+	if l.maxSteps > 0 {
+		l.intp.MaxSteps = l.intp.Steps() + l.maxSteps
+	}
+	if l.maxAlloc > 0 {
+		l.intp.MaxAlloc = l.intp.Allocated() + l.maxAlloc
+	}
+	// an error leaves the environments of the statements it interrupted behind
+	envs := len(l.intp.EnvStack)
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		l.intp.EnvStack = l.intp.EnvStack[:envs]
+		rerr := &Error{Msg: fmt.Sprint(r)}
+		if posErr, isPos := r.(*interp.RuntimeError); isPos {
+			rerr = errorAt(posErr.Token, posErr.Msg)
+		} else if limitErr, isLimit := r.(*interp.LimitError); isLimit {
+			rerr = &Error{Msg: limitErr.Msg, Err: limitErr.Err}
+			if limitErr.Token.Lexeme != "" {
+				rerr = errorAt(limitErr.Token, limitErr.Msg)
+				rerr.Err = limitErr.Err
+			}
+		} else if l.last != nil {
+			rerr = errorAt(*l.last, rerr.Msg)
+		}
+		val, err = Nil(), (*RuntimeError)(rerr)
+	}()
+	return f(), nil
+}
+
+// Save writes the globals to w as a Lox program declaring them again,
+// returning the names of those that cannot be saved, as session.Save does
+func (l *Interpreter) Save(w io.Writer) (skipped []string, err error) {
+	return session.Save(w, &l.intp)
+}
+
+// Restore runs a program written by Save, declaring the globals it saved,
+// which should not be declared already
+func (l *Interpreter) Restore(r io.Reader) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = l.Eval(string(src))
+	return err
+}
+
+// SetGlobal binds name to v in the global environment, replacing any value it had
+func (l *Interpreter) SetGlobal(name string, v Value) {
+	globals := l.intp.EnvStack[0]
+	if box, found := globals.Bindings[name]; found {
+		o := v.Obj()
+		box.Ref = &o
+		return
+	}
+	globals.Bind(name, v.Obj())
+}
+
+// GetGlobal returns the value of a global, if it exists
+func (l *Interpreter) GetGlobal(name string) (Value, bool) {
+	box, found := l.intp.EnvStack[0].Bindings[name]
+	if !found {
+		return Nil(), false
+	}
+	return FromObj(*box.Ref), true
+}
+
+// Globals returns the names of every global, sorted
+func (l *Interpreter) Globals() []string {
+	var names []string
+	for name := range l.intp.EnvStack[0].Bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
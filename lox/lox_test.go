@@ -0,0 +1,335 @@
+//go:build unit
+// +build unit
+
+package lox
+
+import (
+	"context"
+	"errors"
+	"golox/interp"
+	"golox/stdlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	l := New(Options{Globals: map[string]Value{"limit": Number(10)}})
+	if _, err := l.Eval(`var x = limit * 2;`); err != nil {
+		t.Fatal(err)
+	}
+	v, err := l.Eval(`x + 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, isNum := v.AsNumber(); !isNum || n != 21 {
+		t.Fatalf("Expected 21, got %s", v)
+	}
+	v, err = l.Eval(`return "done";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, isStr := v.AsString(); !isStr || s != "done" {
+		t.Fatalf("Expected \"done\", got %s", v)
+	}
+	v, err = l.Eval(`var y = 1;`)
+	if err != nil || !v.IsNil() {
+		t.Fatalf("Expected nil, got %s, %v", v, err)
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.lox")
+	os.WriteFile(path, []byte("fun square(n) { return n * n; }\n"), 0644)
+	l := New(Options{})
+	if _, err := l.EvalFile(path); err != nil {
+		t.Fatal(err)
+	}
+	v, err := l.Call("square", Number(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := v.AsNumber(); n != 9 {
+		t.Fatalf("Expected 9, got %s", v)
+	}
+	if _, err := l.EvalFile(filepath.Join(t.TempDir(), "missing.lox")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected a missing file error, got %v", err)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	var stdout strings.Builder
+	l := New(Options{Stdout: &stdout})
+	if _, err := l.Eval(`fun greet(name) { print name; } greet("lox"); print 1;`); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "lox\n1.000000\n" {
+		t.Fatalf("Expected the program to print to Stdout, got %q", stdout.String())
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	l := New(Options{})
+	_, err := l.Eval("var x = 1;\nprint x")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected a syntax error, got %v", err)
+	}
+	if len(syntaxErr.Errors) != 1 || syntaxErr.Errors[0].Line != 2 {
+		t.Fatalf("Expected 1 error on line 2, got %v", syntaxErr.Errors)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	l := New(Options{})
+	_, err := l.Eval("var x = 1;\nprint y;")
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Expected a runtime error, got %v", err)
+	}
+	expected := `2:1: Variable "y" does not exist in this scope.`
+	if rerr.Error() != expected {
+		t.Fatalf("Expected %q, got %q", expected, rerr.Error())
+	}
+	// the interpreter can still be used after an error
+	if _, err := l.Eval("{ var z = 1; print q; }"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := l.Eval("var z = 2; x = z;"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := New(Options{MaxSteps: 1000})
+	if _, err := l.Eval(`fun spin() { while true {} }`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := l.Eval("var x = 1;\nspin();")
+	expected := "1:14: Evaluation exceeded its step budget."
+	if !errors.Is(err, interp.ErrMaxSteps) || err.Error() != expected {
+		t.Fatalf("Expected %q, got %v", expected, err)
+	}
+	// the budget is per call to Eval
+	for i := 0; i < 3; i++ {
+		if _, err := l.Eval("{ var i = 0; while i < 50 { i = i + 1; } }"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.CallContext(ctx, "spin"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the call to be cancelled, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	l = New(Options{})
+	if _, err := l.EvalContext(ctx, "while true {}"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected evaluation to time out, got %v", err)
+	}
+
+	l = New(Options{MaxDepth: 50})
+	if _, err := l.Eval(`fun down(n) { return down(n + 1); }`); err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Call("down", Number(0))
+	if !errors.Is(err, interp.ErrStackOverflow) || !strings.HasPrefix(err.Error(), "1:22: Stack overflow: more than 50 nested calls.") {
+		t.Fatalf("Expected a stack overflow, got %v", err)
+	}
+	l = New(Options{MaxAlloc: 1000})
+	l.SetGlobal("big", String(strings.Repeat("x", 2000)))
+	if _, err := l.Eval(`var s = big;`); !errors.Is(err, interp.ErrMaxAlloc) {
+		t.Fatalf("Expected the allocation budget to be exhausted, got %v", err)
+	}
+	// the budget is per call to Eval
+	for i := 0; i < 20; i++ {
+		if _, err := l.Eval(`{ var s = "small"; }`); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	l := New(Options{})
+	if _, err := l.Eval(`fun add(a, b) { return a + b; } var notFun = 1;`); err != nil {
+		t.Fatal(err)
+	}
+	v, err := l.Call("add", Number(1), Number(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Equal(Number(3)) {
+		t.Fatalf("Expected 3, got %s", v)
+	}
+	errs := map[string]func() error{
+		`Function "missing" does not exist.`: func() error { _, err := l.Call("missing"); return err },
+		`Function "add" expects 2 arguments, got 1 instead`: func() error {
+			_, err := l.Call("add", Number(1))
+			return err
+		},
+		`1:17: Unable to resolve object to number. Expected: *obj.Num, got: *obj.Str`: func() error {
+			_, err := l.Call("add", Number(1), String("2"))
+			return err
+		},
+		`Unable to call variable "notFun" (of type *obj.Num) as a function.`: func() error {
+			_, err := l.Call("notFun")
+			return err
+		},
+	}
+	for expected, call := range errs {
+		err := call()
+		var rerr *RuntimeError
+		if !errors.As(err, &rerr) || err.Error() != expected {
+			t.Errorf("Expected runtime error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	l := New(Options{})
+	l.SetGlobal("name", String("lox"))
+	l.SetGlobal("name", String("golox"))
+	if _, err := l.Eval(`var greeting = name; name = nil;`); err != nil {
+		t.Fatal(err)
+	}
+	if v, found := l.GetGlobal("greeting"); !found || v.String() != "golox" {
+		t.Fatalf("Expected greeting to be golox, got %s", v)
+	}
+	if v, _ := l.GetGlobal("name"); !v.IsNil() {
+		t.Fatalf("Expected name to be nil, got %s", v)
+	}
+	if _, found := l.GetGlobal("missing"); found {
+		t.Fatal("Expected missing not to be found")
+	}
+	if names := l.Globals(); len(names) != 2 || names[0] != "greeting" || names[1] != "name" {
+		t.Fatalf("Expected globals [greeting name], got %v", names)
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "lib", "math.lox"), []byte("fun square(n) { return n * n; }\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.lox"), []byte(`import "lib/math.lox"; var nine = math.square(3);`), 0644)
+	if _, err := New(Options{}).EvalFile(filepath.Join(dir, "main.lox")); err == nil || !strings.HasSuffix(err.Error(), "Importing modules is not allowed.") {
+		t.Fatalf("Expected imports to be denied by default, got %v", err)
+	}
+	l := New(Options{Imports: true})
+	if _, err := l.EvalFile(filepath.Join(dir, "main.lox")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := l.GetGlobal("nine"); !v.Equal(Number(9)) {
+		t.Fatalf("Expected 9, got %s", v)
+	}
+	// programs not run from a file find modules in the import path
+	l = New(Options{Imports: true, ImportPath: []string{filepath.Join(dir, "lib")}})
+	if v, err := l.Eval(`import "math.lox" as m; m.square(4);`); err != nil || !v.Equal(Number(16)) {
+		t.Fatalf("Expected 16, got %s, %v", v, err)
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	l := New(Options{})
+	l.SetGlobal("limit", Number(10))
+	if _, err := l.Eval(`fun clamp(n) { if (n > limit) { return limit; } return n; }`); err != nil {
+		t.Fatal(err)
+	}
+	var saved strings.Builder
+	if skipped, err := l.Save(&saved); err != nil || len(skipped) != 0 {
+		t.Fatalf("Expected every global to be saved, skipped %v, %v", skipped, err)
+	}
+	restored := New(Options{})
+	if err := restored.Restore(strings.NewReader(saved.String())); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := restored.Call("clamp", Number(12)); err != nil || !v.Equal(Number(10)) {
+		t.Fatalf("Expected 10, got %s, %v", v, err)
+	}
+	// the globals are declared again, so cannot be restored twice
+	if err := restored.Restore(strings.NewReader(saved.String())); err == nil {
+		t.Fatal("Expected an error restoring over existing globals")
+	}
+}
+
+func TestValue(t *testing.T) {
+	var zero Value
+	if !zero.IsNil() || zero.Type() != "nil" || zero.Interface() != nil || zero.Truthy() {
+		t.Fatalf("Expected the zero Value to be nil")
+	}
+	if b, isBool := Bool(true).AsBool(); !isBool || !b || Bool(true).Interface() != true {
+		t.Fatalf("Expected true")
+	}
+	if Number(0).Type() != "number" || !Number(0).Truthy() {
+		t.Fatalf("Expected 0 to be a truthy number")
+	}
+	if _, isNum := String("1").AsNumber(); isNum {
+		t.Fatalf("Expected a string not to be a number")
+	}
+	if String("a").Equal(Number(1)) || !String("a").Equal(String("a")) {
+		t.Fatalf("Wrong string equality")
+	}
+	l := New(Options{})
+	l.Eval(`fun f() {}`)
+	f, _ := l.GetGlobal("f")
+	if !f.IsFunction() || f.Type() != "function" || !f.Equal(f) || f.Equal(Nil()) {
+		t.Fatalf("Expected f to be a function equal to itself, got %s", f)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	if _, err := New(Options{}).Eval(`sqrt(4);`); err == nil {
+		t.Fatal("Expected no builtins without permissions")
+	}
+	l := New(Options{Permissions: stdlib.Defaults()})
+	if v, err := l.Eval(`sqrt(4);`); err != nil || !v.Equal(Number(2)) {
+		t.Fatalf("Expected 2, got %s, %v", v, err)
+	}
+	if v, err := l.Call("sqrt", Number(9)); err != nil || !v.Equal(Number(3)) {
+		t.Fatalf("Expected 3, got %s, %v", v, err)
+	}
+	_, err := l.Eval(`readFile("/etc/passwd");`)
+	expected := `1:1: Permission denied: readFile needs the "read" capability.`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected %q, got %v", expected, err)
+	}
+	if names := l.Globals(); len(names) != 0 {
+		t.Fatalf("Expected builtins not to be globals, got %v", names)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	double, err := Func("double", func(n float64) float64 { return n * 2 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	prelude := NewPrelude(stdlib.Defaults(), map[string]Value{"double": double})
+	var wg sync.WaitGroup
+	results := make([]float64, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l := New(Options{Prelude: prelude, Globals: map[string]Value{"n": Number(float64(i))}})
+			if _, err := l.Eval(`fun fib(n) { if n < 2 { return n; } return fib(n - 1) + fib(n - 2); }`); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := l.Eval(`var result = double(fib(10)) + sqrt(n * n);`); err != nil {
+				t.Error(err)
+				return
+			}
+			v, _ := l.GetGlobal("result")
+			results[i], _ = v.AsNumber()
+		}(i)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if result != float64(110+i) {
+			t.Errorf("Expected interpreter %d to compute %d, got %g", i, 110+i, result)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package lox
+
+import (
+	"fmt"
+	"golox/obj"
+	"reflect"
+	"sort"
+)
+
+// A Go struct exposed to Lox through a pointer to it
+type object struct {
+	ptr reflect.Value
+}
+
+// Object returns a Lox object for ptr, a pointer to a Go struct.
+// Its exported fields are properties that scripts read and assign with '.',
+// and its exported methods are called as Lox functions, converted as Func does.
+// Objects are equal if they point to the same struct.
+func Object(ptr interface{}) (Value, error) {
+	v := reflect.ValueOf(ptr)
+	if !isStructPtr(v.Type()) || v.IsNil() {
+		return Nil(), fmt.Errorf("cannot expose %T: expected a non-nil pointer to a struct", ptr)
+	}
+	return Value{&object{v}}, nil
+}
+
+// RegisterType binds a global function named name returning a new object
+// for the zero value of the struct type of sample, which may be a pointer to it
+func (l *Interpreter) RegisterType(name string, sample interface{}) error {
+	t := reflect.TypeOf(sample)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot register %q: expected a struct, got %T", name, sample)
+	}
+	l.SetGlobal(name, Value{&obj.Builtin{Name: name, Arity: 0, Fn: func([]obj.Obj) obj.Obj {
+		return &object{reflect.New(t)}
+	}}})
+	return nil
+}
+
+// Returns if t is a pointer to a struct
+func isStructPtr(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
+
+func (o *object) Type() obj.ObjType { return obj.OBJECT_OBJ }
+
+// String uses the struct's String method if it has one,
+// or shows its type and fields
+func (o *object) String() string {
+	if s, isStringer := o.ptr.Interface().(fmt.Stringer); isStringer {
+		return s.String()
+	}
+	return fmt.Sprintf("%s%+v", o.typeName(), o.ptr.Elem().Interface())
+}
+
+func (o *object) typeName() string {
+	return o.ptr.Elem().Type().Name()
+}
+
+// Returns the exported field with the name, if there is one
+func (o *object) field(name string) (reflect.Value, bool) {
+	f, found := o.ptr.Elem().Type().FieldByName(name)
+	if !found || !f.IsExported() {
+		return reflect.Value{}, false
+	}
+	return o.ptr.Elem().FieldByIndex(f.Index), true
+}
+
+func (o *object) Get(name string) obj.Obj {
+	if field, found := o.field(name); found {
+		if !convertible(field.Type()) {
+			panic(fmt.Sprintf("Property %q of %s has the unsupported type %s.", name, o.typeName(), field.Type()))
+		}
+		return fromGo(field)
+	}
+	if method := o.ptr.MethodByName(name); method.IsValid() {
+		fn, err := Func(name, method.Interface())
+		if err != nil {
+			panic(fmt.Sprintf("Method %q of %s cannot be called from Lox: %s.", name, o.typeName(), err))
+		}
+		return fn.Obj()
+	}
+	panic(fmt.Sprintf("Undefined property %q of %s.", name, o.typeName()))
+}
+
+func (o *object) Set(name string, val obj.Obj) {
+	field, found := o.field(name)
+	if !found {
+		if o.ptr.MethodByName(name).IsValid() {
+			panic(fmt.Sprintf("Cannot assign to method %q of %s.", name, o.typeName()))
+		}
+		panic(fmt.Sprintf("Undefined property %q of %s.", name, o.typeName()))
+	}
+	if !convertible(field.Type()) {
+		panic(fmt.Sprintf("Property %q of %s has the unsupported type %s.", name, o.typeName(), field.Type()))
+	}
+	v, err := toGo(val, field.Type())
+	if err != "" {
+		panic(fmt.Sprintf("Property %q of %s must be %s.", name, o.typeName(), err))
+	}
+	field.Set(v)
+}
+
+func (o *object) Properties() []string {
+	var names []string
+	for _, f := range reflect.VisibleFields(o.ptr.Elem().Type()) {
+		if f.IsExported() && !f.Anonymous {
+			names = append(names, f.Name)
+		}
+	}
+	for i := 0; i < o.ptr.NumMethod(); i++ {
+		names = append(names, o.ptr.Type().Method(i).Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o *object) Equal(other obj.Obj) bool {
+	otherObject, isObject := other.(*object)
+	return isObject && o.ptr.Type() == otherObject.ptr.Type() && o.ptr.Pointer() == otherObject.ptr.Pointer()
+}
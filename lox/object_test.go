@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type header struct {
+	Name, Value string
+}
+
+func (h *header) String() string { return h.Name + ": " + h.Value }
+
+type request struct {
+	Method  string
+	Retries int
+	Header  *header
+	hidden  bool
+	Tags    []string
+}
+
+func (r *request) Retry() int {
+	r.Retries++
+	return r.Retries
+}
+
+func (r *request) Is(method string) bool { return strings.EqualFold(r.Method, method) }
+
+func (r *request) Fail() error { return errors.New("connection refused") }
+
+func (r *request) Channel() chan int { return nil }
+
+func TestObject(t *testing.T) {
+	req := &request{Method: "GET", Header: &header{"Accept", "text/plain"}}
+	v, err := Object(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(Options{Globals: map[string]Value{"req": v}})
+	tests := []struct {
+		src, expected string
+	}{
+		{`req.Method;`, "GET"},
+		{`req.Retry(); req.Retry();`, "2.000000"},
+		{`req.Retries = 5; req.Retries;`, "5.000000"},
+		{`req.Is("get");`, "true"},
+		{`req.Header.Value;`, "text/plain"},
+		{`req.Header;`, "Accept: text/plain"},
+		{`req.Header.Value = "*/*"; req.Header;`, "Accept: */*"},
+		{`req == req;`, "true"},
+		{`req.Header == req.Header;`, "true"},
+		{`req == req.Header;`, "false"},
+		{`req == 1;`, "false"},
+		{`var h = req.Header; req.Header = nil; h.Name;`, "Accept"},
+	}
+	for _, tt := range tests {
+		v, err := l.Eval(tt.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		if v.String() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.src, tt.expected, v)
+		}
+	}
+	if req.Retries != 5 || req.Header != nil {
+		t.Fatalf("Expected the script to change the struct, got %+v", req)
+	}
+	if v.Type() != "object" || v.Interface() != req {
+		t.Fatalf("Expected an object for the request, got %s %v", v.Type(), v.Interface())
+	}
+	if s := v.String(); !strings.HasPrefix(s, "request{Method:GET") {
+		t.Fatalf("Expected the type and fields when printed, got %s", s)
+	}
+}
+
+func TestObjectErrors(t *testing.T) {
+	v, _ := Object(&request{})
+	l := New(Options{Globals: map[string]Value{"req": v}})
+	tests := map[string]string{
+		`req.missing;`:       `1:5: Undefined property "missing" of request.`,
+		`req.hidden;`:        `1:5: Undefined property "hidden" of request.`,
+		`req.Missing = 1;`:   `1:5: Undefined property "Missing" of request.`,
+		`req.Retry = 1;`:     `1:5: Cannot assign to method "Retry" of request.`,
+		`req.Retries = "1";`: `1:5: Property "Retries" of request must be a number, got string.`,
+		`req.Header = req;`:  `1:5: Property "Header" of request must be a header object, got object.`,
+		`req.Tags;`:          `1:5: Property "Tags" of request has the unsupported type []string.`,
+		`req.Is(1);`:         `1:5: Argument 1 of "Is" must be a string, got number.`,
+		`req.Is();`:          `1:5: Function "Is" expects 1 arguments, got 0 instead`,
+		`req.Fail();`:        `1:5: connection refused`,
+		`req.Channel();`:     `1:5: Method "Channel" of request cannot be called from Lox: cannot bind "Channel": unsupported result type chan int.`,
+		`req.Header.Name;`:   `1:12: Only objects have properties, got nil.`,
+	}
+	for src, expected := range tests {
+		_, err := l.Eval(src)
+		var rerr *RuntimeError
+		if !errors.As(err, &rerr) || err.Error() != expected {
+			t.Errorf("%s: expected runtime error %q, got %v", src, expected, err)
+		}
+	}
+	for _, invalid := range []interface{}{request{}, (*request)(nil), 1, new(int)} {
+		if _, err := Object(invalid); err == nil {
+			t.Errorf("Expected %T not to be exposed", invalid)
+		}
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	l := New(Options{})
+	if err := l.RegisterType("Header", header{}); err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	l.Register("send", func(h *header) string {
+		sent = append(sent, h.String())
+		return fmt.Sprint(len(sent))
+	})
+	l.Register("newHeader", func(name string) *header { return &header{Name: name} })
+	v, err := l.Eval(`var h = Header(); h.Name = "Host"; h.Value = "lox"; send(h);
+var empty = newHeader("Empty"); send(empty);`)
+	if err != nil || v.String() != "2" {
+		t.Fatalf("Expected 2 headers to be sent, got %s, %v", v, err)
+	}
+	if strings.Join(sent, ", ") != "Host: lox, Empty: " {
+		t.Fatalf("Unexpected headers sent: %q", sent)
+	}
+	if err := l.RegisterType("Number", 1); err == nil {
+		t.Fatal("Expected registering a number to fail")
+	}
+	if _, err := l.Eval(`send(1);`); err == nil || err.Error() != `1:1: Argument 1 of "send" must be a header object, got number.` {
+		t.Fatalf("Unexpected error sending a number: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package lox
+
+import (
+	"golox/interp"
+	"golox/obj"
+)
+
+// Value is a Lox value: nil, a number, a boolean, a string, a function or an object.
+// The zero Value is nil.
+type Value struct {
+	o obj.Obj
+}
+
+// Nil returns the Lox nil
+func Nil() Value { return Value{&obj.Nil{}} }
+
+// Number returns a Lox number
+func Number(n float64) Value { return Value{&obj.Num{Value: n}} }
+
+// Bool returns a Lox boolean
+func Bool(b bool) Value { return Value{&obj.Bool{Value: b}} }
+
+// String returns a Lox string
+func String(s string) Value { return Value{&obj.Str{Value: s}} }
+
+// FromObj wraps a value of the interpreter
+func FromObj(o obj.Obj) Value {
+	if rv, isRetVal := o.(*obj.RetVal); isRetVal {
+		o = rv.Val
+	}
+	return Value{o}
+}
+
+// Obj returns the value as used by the interpreter
+func (v Value) Obj() obj.Obj {
+	if v.o == nil {
+		return &obj.Nil{}
+	}
+	return v.o
+}
+
+// Type returns the name of the value's type: "nil", "number", "bool", "string", "function" or "object"
+func (v Value) Type() string {
+	return v.Obj().Type().String()
+}
+
+// IsNil returns if the value is nil
+func (v Value) IsNil() bool {
+	_, isNil := v.Obj().(*obj.Nil)
+	return isNil
+}
+
+// AsNumber returns the value if it is a number
+func (v Value) AsNumber() (float64, bool) {
+	n, isNum := v.o.(*obj.Num)
+	if !isNum {
+		return 0, false
+	}
+	return n.Value, true
+}
+
+// AsBool returns the value if it is a boolean
+func (v Value) AsBool() (bool, bool) {
+	b, isBool := v.o.(*obj.Bool)
+	if !isBool {
+		return false, false
+	}
+	return b.Value, true
+}
+
+// AsString returns the value if it is a string
+func (v Value) AsString() (string, bool) {
+	s, isStr := v.o.(*obj.Str)
+	if !isStr {
+		return "", false
+	}
+	return s.Value, true
+}
+
+// IsFunction returns if the value can be called
+func (v Value) IsFunction() bool {
+	switch v.o.(type) {
+	case *obj.Closure, *obj.Builtin:
+		return true
+	}
+	return false
+}
+
+// Truthy returns if the value counts as true in a condition: everything but nil and false
+func (v Value) Truthy() bool {
+	return interp.IsTruthy(v.Obj())
+}
+
+// Equal returns if the values are equal with ==, comparing functions by identity
+func (v Value) Equal(other Value) bool {
+	if v.IsFunction() || other.IsFunction() {
+		return v.o == other.o
+	}
+	return interp.IsEqual(v.Obj(), other.Obj())
+}
+
+// Interface returns the value as a Go value: nil, a float64, a bool, a string,
+// the pointer to the struct of an object, or the interpreter's value for functions
+func (v Value) Interface() interface{} {
+	switch o := v.Obj().(type) {
+	case *obj.Nil:
+		return nil
+	case *obj.Num:
+		return o.Value
+	case *obj.Bool:
+		return o.Value
+	case *obj.Str:
+		return o.Value
+	case *object:
+		return o.ptr.Interface()
+	default:
+		return o
+	}
+}
+
+// String returns the value as print shows it
+func (v Value) String() string {
+	return v.Obj().String()
+}
@@ -0,0 +1,204 @@
+// Package loxtest runs tests written in Lox.
+// Tests are top-level functions named test* without parameters,
+// declared in files named *_test.lox.
+package loxtest
+
+import (
+	"context"
+	"fmt"
+	"golox/ast"
+	"golox/interp"
+	"golox/obj"
+	"golox/stdlib"
+	"golox/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of a test
+type Result struct {
+	File     string
+	Name     string
+	Line     int    // line the test is declared on, counted from 0
+	Failure  string // why the test failed, or empty if it passed
+	Pos      token.Token
+	Duration time.Duration
+}
+
+// Passed returns if the test passed
+func (r *Result) Passed() bool {
+	return r.Failure == ""
+}
+
+// Location returns where the test failed as file:line:column, counted from 1
+func (r *Result) Location() string {
+	return fmt.Sprintf("%s:%d:%d", r.File, r.Pos.Line+1, r.Pos.LineOffset+1)
+}
+
+// Options control which tests run and how
+type Options struct {
+	Run    *regexp.Regexp // only run tests with matching names, if set
+	Tracer interp.Tracer  // installed in the interpreter of every test, if set
+	Stdout io.Writer      // where tests print, if set instead of os.Stdout
+	// Timeout and MaxSteps, if positive, limit how long each test may run
+	// and how many nodes it may evaluate before failing
+	Timeout  time.Duration
+	MaxSteps int
+	// Permissions grant tests groups of builtin functions, as stdlib.Install does,
+	// and let them import modules if they grant stdlib.Read, as stdlib.LimitImports does.
+	// If nil, no builtins besides the assertions are declared.
+	Permissions stdlib.Permissions
+	// ImportPath lists the directories searched for modules not found next to the test file
+	ImportPath []string
+}
+
+// Files returns every file named *_test.lox under the directories in paths,
+// along with the paths that are files, sorted
+func Files(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if file == path && !info.IsDir() {
+				files = append(files, file)
+			} else if !info.IsDir() && strings.HasSuffix(file, "_test.lox") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Tests returns the test functions declared at the top level of prog
+func Tests(prog *ast.Program) []*ast.FuncDeclStmt {
+	var tests []*ast.FuncDeclStmt
+	for _, stmt := range prog.Statements {
+		decl, isDecl := stmt.(*ast.FuncDeclStmt)
+		if isDecl && decl != nil && strings.HasPrefix(decl.Name.String(), "test") {
+			tests = append(tests, decl)
+		}
+	}
+	return tests
+}
+
+// RunFile runs the tests of prog, parsed from the file at path.
+// Each test runs in a fresh interpreter, after the whole program.
+func RunFile(path string, prog *ast.Program, opts Options) []*Result {
+	var results []*Result
+	for _, test := range Tests(prog) {
+		if opts.Run != nil && !opts.Run.MatchString(test.Name.String()) {
+			continue
+		}
+		results = append(results, runTest(path, prog, test, opts))
+	}
+	return results
+}
+
+func runTest(path string, prog *ast.Program, test *ast.FuncDeclStmt, opts Options) (result *Result) {
+	result = &Result{File: path, Name: test.Name.String(), Line: test.Token.Line, Pos: test.Token}
+	if len(test.Params) > 0 {
+		result.Failure = "Test functions must not have parameters."
+		return result
+	}
+
+	intp := interp.New()
+	if opts.Permissions != nil {
+		stdlib.Install(&intp, opts.Permissions)
+	}
+	// in the prelude, like the builtins of stdlib, so test files can declare their own
+	if intp.Prelude.Bindings == nil {
+		intp.Prelude = obj.NewEnv()
+	}
+	for _, b := range Builtins() {
+		intp.Prelude.Bind(b.Name, b)
+	}
+	stdlib.LimitImports(&intp, opts.Permissions)
+	intp.Tracer = opts.Tracer
+	intp.File, intp.ImportPath = path, opts.ImportPath
+	if opts.Stdout != nil {
+		intp.Stdout = opts.Stdout
+	}
+	intp.MaxSteps = opts.MaxSteps
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	// errors without a position are reported at the last statement started
+	last := test.Token
+	intp.OnStmt = func(_ *interp.Interpreter, stmt ast.Stmt) {
+		last = ast.StmtToken(stmt)
+	}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+		if r := recover(); r != nil {
+			if rerr, isRuntime := r.(*interp.RuntimeError); isRuntime {
+				result.Failure, result.Pos = rerr.Msg, rerr.Token
+			} else if lerr, isLimit := r.(*interp.LimitError); isLimit {
+				result.Failure, result.Pos = lerr.Msg, last
+			} else {
+				result.Failure, result.Pos = fmt.Sprint(r), last
+			}
+		}
+	}()
+	intp.EvalContext(ctx, prog)
+	intp.EvalContext(ctx, &ast.CallExpr{Token: test.Name.Token, Function: test.Name})
+	return result
+}
+
+// Builtins returns the functions available to tests
+func Builtins() []*obj.Builtin {
+	return []*obj.Builtin{
+		{Name: "assert", Arity: 1, Fn: func(args []obj.Obj) obj.Obj {
+			if !interp.IsTruthy(args[0]) {
+				panic(fmt.Sprintf("Assertion failed: got %s.", show(args[0])))
+			}
+			return nil
+		}},
+		{Name: "assertEqual", Arity: 2, Fn: func(args []obj.Obj) obj.Obj {
+			if !equal(args[0], args[1]) {
+				panic(fmt.Sprintf("Expected %s, got %s.", show(args[1]), show(args[0])))
+			}
+			return nil
+		}},
+		{Name: "fail", Arity: 1, Fn: func(args []obj.Obj) obj.Obj {
+			panic(args[0].String())
+		}},
+	}
+}
+
+// Returns if two values are equal, comparing functions by identity
+func equal(a, b obj.Obj) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a := a.(type) {
+	case *obj.Closure, *obj.Builtin:
+		return a == b
+	case obj.Object:
+		return a.Equal(b)
+	}
+	return interp.IsEqual(a, b)
+}
+
+// Returns a value as written in Lox source
+func show(o obj.Obj) string {
+	if s, isStr := o.(*obj.Str); isStr {
+		return fmt.Sprintf("%q", s.Value)
+	}
+	return o.String()
+}
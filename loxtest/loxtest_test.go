@@ -0,0 +1,180 @@
+//go:build unit
+// +build unit
+
+package loxtest
+
+import (
+	"bytes"
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const source = `var calls = 0;
+fun double(n) {
+    calls = calls + 1;
+    return n * 2;
+}
+fun testDouble() {
+    assertEqual(double(2), 4);
+    assertEqual(calls, 1);
+}
+fun testWrong() {
+    assertEqual(double(2), 5);
+}
+fun testAssert() {
+    assert(double(0) == 1);
+}
+fun testError() {
+    print nope;
+}
+fun testParams(x) {}
+fun helper() {
+    fail("not a test");
+}
+`
+
+func parse(t *testing.T, src string) *ast.Program {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	return prog
+}
+
+func TestRunFile(t *testing.T) {
+	results := RunFile("a_test.lox", parse(t, source), Options{})
+	want := []struct {
+		name, failure, location string
+	}{
+		{"testDouble", "", "a_test.lox:6:5"},
+		{"testWrong", "Expected 5.000000, got 4.000000.", "a_test.lox:11:5"},
+		{"testAssert", "Assertion failed: got false.", "a_test.lox:14:5"},
+		{"testError", `Variable "nope" does not exist in this scope.`, "a_test.lox:17:5"},
+		{"testParams", "Test functions must not have parameters.", "a_test.lox:19:1"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Name != w.name || r.Failure != w.failure {
+			t.Errorf("Expected %s to fail with %q, got %s failing with %q", w.name, w.failure, r.Name, r.Failure)
+		}
+		if !r.Passed() && r.Location() != w.location {
+			t.Errorf("Expected %s to fail at %s, got %s", w.name, w.location, r.Location())
+		}
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	results := RunFile("a_test.lox", parse(t, source), Options{Run: regexp.MustCompile("Double|Wrong")})
+	if len(results) != 2 || results[0].Name != "testDouble" || results[1].Name != "testWrong" {
+		t.Fatalf("Expected testDouble and testWrong to run, got %v", results)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a_test.lox", "b.lox", "sub/c_test.lox", "sub/d_test.txt"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := Files([]string{dir, filepath.Join(dir, "b.lox")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a_test.lox"), filepath.Join(dir, "b.lox"), filepath.Join(dir, "sub/c_test.lox")}
+	if strings.Join(files, " ") != strings.Join(want, " ") {
+		t.Fatalf("Expected files %v, got %v", want, files)
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	results := RunFile("a_test.lox", parse(t, source), Options{Run: regexp.MustCompile("Double|Wrong")})
+	var out bytes.Buffer
+	WriteTAP(&out, results)
+	want := `TAP version 13
+1..2
+ok 1 - a_test.lox testDouble
+not ok 2 - a_test.lox testWrong
+  ---
+  message: "Expected 5.000000, got 4.000000."
+  at: "a_test.lox:11:5"
+  ...
+`
+	if out.String() != want {
+		t.Fatalf("Expected TAP output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := RunFile("a_test.lox", parse(t, source), Options{Run: regexp.MustCompile("Double|Wrong")})
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<testsuites tests="2" failures="1"`,
+		`<testsuite name="a_test.lox" tests="2" failures="1"`,
+		`<testcase name="testDouble" classname="a_test"`,
+		`<failure message="Expected 5.000000, got 4.000000.">a_test.lox:11:5: Expected 5.000000, got 4.000000.</failure>`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected JUnit output to contain %s, got:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestStdout(t *testing.T) {
+	var stdout strings.Builder
+	prog := parse(t, `print "setup"; fun testPrint() { print "test"; }`)
+	results := RunFile("a_test.lox", prog, Options{Stdout: &stdout})
+	if len(results) != 1 || !results[0].Passed() {
+		t.Fatalf("Expected testPrint to pass, got %v", results)
+	}
+	if stdout.String() != "setup\ntest\n" {
+		t.Fatalf("Expected the test to print to Stdout, got %q", stdout.String())
+	}
+}
+
+func TestShadowAssertions(t *testing.T) {
+	prog := parse(t, `fun fail(msg) { return msg; }
+fun testOwnFail() {
+    assertEqual(fail("ok"), "ok");
+}`)
+	results := RunFile("a_test.lox", prog, Options{})
+	if len(results) != 1 || !results[0].Passed() {
+		t.Fatalf("Expected testOwnFail to pass, got %v", results)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	prog := parse(t, `fun testSpin() {
+    while true {}
+}
+fun testQuick() {
+    assert(true);
+}`)
+	for _, opts := range []Options{{Timeout: 10 * time.Millisecond}, {MaxSteps: 1000}} {
+		results := RunFile("a_test.lox", prog, opts)
+		// the limits apply to each test on its own
+		if len(results) != 2 || results[0].Passed() || !results[1].Passed() {
+			t.Fatalf("Expected only testSpin to fail with %+v, got %v", opts, results)
+		}
+		if results[0].Pos.Line != 1 || !strings.HasPrefix(results[0].Failure, "Evaluation") {
+			t.Errorf("Expected testSpin to be stopped on line 1, got %s at %s", results[0].Failure, results[0].Location())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package loxtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteText writes every failure, and with verbose every test passed,
+// followed by a summary line
+func WriteText(w io.Writer, results []*Result, verbose bool) error {
+	failed := 0
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		if !r.Passed() {
+			failed++
+			fmt.Fprintf(w, "--- FAIL: %s (%s:%d)\n", r.Name, r.File, r.Line+1)
+			fmt.Fprintf(w, "    %s: %s\n", r.Location(), r.Failure)
+		} else if verbose {
+			fmt.Fprintf(w, "--- PASS: %s (%s:%d)\n", r.Name, r.File, r.Line+1)
+		}
+	}
+	status := "PASS"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d passed, %d failed (%.3fs)\n", status, len(results)-failed, failed, total.Seconds())
+	return err
+}
+
+// WriteTAP writes the results in the Test Anything Protocol, version 13
+func WriteTAP(w io.Writer, results []*Result) error {
+	fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(results))
+	for i, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "ok %d - %s %s\n", i+1, r.File, r.Name)
+			continue
+		}
+		fmt.Fprintf(w, "not ok %d - %s %s\n", i+1, r.File, r.Name)
+		fmt.Fprintf(w, "  ---\n  message: %q\n  at: %q\n  ...\n", r.Failure, r.Location())
+	}
+	return nil
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite per file
+func WriteJUnit(w io.Writer, results []*Result) error {
+	report := junitSuites{}
+	var total time.Duration
+	var suiteTimes []time.Duration
+	for _, r := range results {
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != r.File {
+			report.Suites = append(report.Suites, junitSuite{Name: r.File})
+			suiteTimes = append(suiteTimes, 0)
+		}
+		suite := &report.Suites[len(report.Suites)-1]
+		c := junitCase{Name: r.Name, Classname: strings.TrimSuffix(r.File, ".lox"), Time: seconds(r.Duration)}
+		if !r.Passed() {
+			c.Failure = &junitFailure{Message: r.Failure, Text: r.Location() + ": " + r.Failure}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		report.Tests++
+		suiteTimes[len(suiteTimes)-1] += r.Duration
+		total += r.Duration
+	}
+	for i := range report.Suites {
+		report.Suites[i].Time = seconds(suiteTimes[i])
+	}
+	report.Time = seconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Message is a JSON-RPC request or notification read from the client,
+// where notifications have no ID
+type Message struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+// ResponseError is the error of a failed request
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Conn reads and writes messages framed by Content-Length headers
+type Conn struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewConn returns a Conn reading from r and writing to w
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{r: bufio.NewReader(r), w: w}
+}
+
+// Read returns the next message
+func (c *Conn) Read() (*Message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	msg := &Message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Reply sends the result of the request with the given ID
+func (c *Conn) Reply(id *json.RawMessage, result interface{}) error {
+	return c.write(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  interface{}      `json:"result"`
+	}{"2.0", id, result})
+}
+
+// ReplyError sends the error of the failed request with the given ID
+func (c *Conn) ReplyError(id *json.RawMessage, code int, msg string) error {
+	return c.write(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Error   ResponseError    `json:"error"`
+	}{"2.0", id, ResponseError{code, msg}})
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(method string, params interface{}) error {
+	return c.write(struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{"2.0", method, params})
+}
+
+// Writes a message with its header
+func (c *Conn) write(msg interface{}) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", body.Len()); err != nil {
+		return err
+	}
+	_, err := c.w.Write(body.Bytes())
+	return err
+}
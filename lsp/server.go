@@ -0,0 +1,430 @@
+// Package lsp implements a Language Server Protocol server for Lox
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golox/ast"
+	"golox/lexer"
+	"golox/parser"
+	"golox/scope"
+	"golox/token"
+	"io"
+	"strings"
+)
+
+// Server answers requests about the open documents of one client
+type Server struct {
+	conn     *Conn
+	docs     map[string]*document
+	shutdown bool
+	utf8     bool // if the client counts the characters of positions in bytes, as tokens do, instead of UTF-16
+}
+
+// A document and the result of analyzing its latest text
+type document struct {
+	text      string
+	lines     []string
+	utf16     bool // if the client counts the characters of positions in UTF-16 code units
+	prog      *ast.Program
+	info      *scope.Info
+	lexErrs   []lexer.LexError
+	parseErrs []parser.ParserError
+}
+
+// Returns the analysis of text, whose positions the client counts in UTF-16 code units if utf16 is set
+func analyze(text string, utf16 bool) *document {
+	l := lexer.NewLexer(text)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	return &document{
+		text:      text,
+		lines:     strings.Split(text, "\n"),
+		utf16:     utf16,
+		prog:      prog,
+		info:      scope.Resolve(prog),
+		lexErrs:   l.Errors(),
+		parseErrs: p.Errors(),
+	}
+}
+
+// Serve runs a server reading requests from r and writing to w
+// until the client sends the exit notification
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{conn: NewConn(r, w), docs: make(map[string]*document)}
+	for {
+		msg, err := s.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if rerr != nil {
+			err = s.conn.ReplyError(msg.ID, rerr.Code, rerr.Message)
+		} else {
+			err = s.conn.Reply(msg.ID, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Decodes request params into v
+func decode(params json.RawMessage, v interface{}) *ResponseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &ResponseError{codeInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+// Handles a request or notification, returning the result for requests
+func (s *Server) handle(msg *Message) (interface{}, *ResponseError) {
+	switch msg.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		// UTF-16 is the default, but tokens count bytes, which saves converting positions
+		encoding := encodingUTF16
+		for _, enc := range params.Capabilities.General.PositionEncodings {
+			if enc == encodingUTF8 {
+				encoding = encodingUTF8
+			}
+		}
+		s.utf8 = encoding == encodingUTF8
+		res := InitializeResult{Capabilities: ServerCapabilities{
+			PositionEncoding:       encoding,
+			TextDocumentSync:       syncFull,
+			DocumentSymbolProvider: true,
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			CompletionProvider:     map[string]interface{}{},
+		}}
+		res.ServerInfo.Name = "golox"
+		return res, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			// full sync, so the last change holds the whole text
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.conn.Notify("textDocument/publishDiagnostics",
+			PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.doc(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return documentSymbols(doc, doc.prog.Statements), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.doc(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return hover(doc, params.Position), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.doc(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		sym := doc.symbolAt(params.Position)
+		if sym == nil {
+			return nil, nil
+		}
+		return Location{params.TextDocument.URI, doc.tokenRange(sym.Decl)}, nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.doc(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		locs := []Location{}
+		sym := doc.symbolAt(params.Position)
+		if sym == nil {
+			return locs, nil
+		}
+		if params.Context.IncludeDeclaration {
+			locs = append(locs, Location{params.TextDocument.URI, doc.tokenRange(sym.Decl)})
+		}
+		for _, ref := range sym.Refs {
+			locs = append(locs, Location{params.TextDocument.URI, doc.tokenRange(ref)})
+		}
+		return locs, nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.doc(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return completion(doc, params.Position), nil
+	}
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		// unknown notifications are ignored
+		return nil, nil
+	}
+	return nil, &ResponseError{codeMethodNotFound, fmt.Sprintf("Method %q not found", msg.Method)}
+}
+
+// Returns the open document at uri
+func (s *Server) doc(uri string) (*document, *ResponseError) {
+	doc, found := s.docs[uri]
+	if !found {
+		return nil, &ResponseError{codeInvalidParams, fmt.Sprintf("Document %q is not open", uri)}
+	}
+	return doc, nil
+}
+
+// Analyzes the new text of a document and publishes its diagnostics
+func (s *Server) update(uri string, text string) {
+	doc := analyze(text, !s.utf8)
+	s.docs[uri] = doc
+	s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics(doc)})
+}
+
+// Returns the range covered by a token, in the client's encoding
+func (doc *document) tokenRange(tok token.Token) Range {
+	length := len(tok.Lexeme)
+	if length == 0 {
+		length = 1
+	}
+	return Range{
+		Start: doc.position(tok.Line, tok.LineOffset),
+		End:   doc.position(tok.Line, tok.LineOffset+length),
+	}
+}
+
+// Returns the position of the byte at offset in a line, in the client's encoding
+func (doc *document) position(line, offset int) Position {
+	if !doc.utf16 || line < 0 || line >= len(doc.lines) {
+		return Position{line, offset}
+	}
+	text := doc.lines[line]
+	if offset > len(text) {
+		return Position{line, utf16Len(text) + offset - len(text)}
+	}
+	return Position{line, utf16Len(text[:offset])}
+}
+
+// Returns the byte offset in its line of a position in the client's encoding
+func (doc *document) offset(pos Position) int {
+	if !doc.utf16 || pos.Line < 0 || pos.Line >= len(doc.lines) {
+		return pos.Character
+	}
+	text := doc.lines[pos.Line]
+	units := 0
+	for i, r := range text {
+		if units >= pos.Character {
+			return i
+		}
+		units += utf16Len(string(r))
+	}
+	return len(text) + pos.Character - units
+}
+
+// Returns the symbol named at a position in the client's encoding, or nil
+func (doc *document) symbolAt(pos Position) *scope.Symbol {
+	return doc.info.SymbolAt(pos.Line, doc.offset(pos))
+}
+
+// Returns how many UTF-16 code units encode s
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns the lexer and parser errors of a document
+func diagnostics(doc *document) []Diagnostic {
+	diags := []Diagnostic{}
+	for _, e := range doc.lexErrs {
+		diags = append(diags, Diagnostic{doc.tokenRange(e.Token), SeverityError, "golox", e.Msg})
+	}
+	for _, e := range doc.parseErrs {
+		diags = append(diags, Diagnostic{doc.tokenRange(e.Token), SeverityError, "golox", e.Error()})
+	}
+	return diags
+}
+
+// Returns the signature of a function declaration
+func signature(fn *ast.FuncDeclStmt) string {
+	params := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = p.String() + ast.Annotation(fn.ParamType(i))
+	}
+	return fmt.Sprintf("fun %s(%s)%s", fn.Name, strings.Join(params, ", "), ast.Annotation(fn.ReturnType))
+}
+
+// Returns the symbols declared by stmts, with functions containing their locals
+func documentSymbols(doc *document, stmts []ast.Stmt) []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.VarStmt:
+			if stmt == nil || stmt.Name == nil {
+				continue
+			}
+			syms = append(syms, DocumentSymbol{
+				Name:           stmt.Name.String(),
+				Kind:           SymbolKindVariable,
+				Range:          Range{doc.tokenRange(stmt.Token).Start, doc.tokenRange(stmt.Name.Token).End},
+				SelectionRange: doc.tokenRange(stmt.Name.Token),
+			})
+		case *ast.ImportStmt:
+			if stmt == nil {
+				continue
+			}
+			// modules without an alias are named by their path
+			nameTok := stmt.Path
+			if stmt.Alias != nil {
+				nameTok = stmt.Alias.Token
+			}
+			syms = append(syms, DocumentSymbol{
+				Name:           stmt.Name().String(),
+				Detail:         stmt.Path.Lexeme,
+				Kind:           SymbolKindModule,
+				Range:          Range{doc.tokenRange(stmt.Token).Start, doc.tokenRange(nameTok).End},
+				SelectionRange: doc.tokenRange(nameTok),
+			})
+		case *ast.FuncDeclStmt:
+			if stmt == nil || stmt.Name == nil || stmt.Body == nil {
+				continue
+			}
+			syms = append(syms, DocumentSymbol{
+				Name:           stmt.Name.String(),
+				Detail:         signature(stmt),
+				Kind:           SymbolKindFunction,
+				Range:          Range{doc.tokenRange(stmt.Token).Start, doc.tokenRange(stmt.Body.EndToken).End},
+				SelectionRange: doc.tokenRange(stmt.Name.Token),
+				Children:       documentSymbols(doc, stmt.Body.Statements),
+			})
+		case *ast.BlockStmt:
+			if stmt != nil {
+				syms = append(syms, documentSymbols(doc, stmt.Statements)...)
+			}
+		case *ast.IfStmt:
+			if stmt == nil {
+				continue
+			}
+			if stmt.OnTrue != nil {
+				syms = append(syms, documentSymbols(doc, stmt.OnTrue.Statements)...)
+			}
+			if stmt.OnFalse != nil {
+				syms = append(syms, documentSymbols(doc, stmt.OnFalse.Statements)...)
+			}
+		case *ast.WhileStmt:
+			if stmt != nil && stmt.Body != nil {
+				syms = append(syms, documentSymbols(doc, stmt.Body.Statements)...)
+			}
+		}
+	}
+	return syms
+}
+
+// Returns hover information for the name at pos, or nil
+func hover(doc *document, pos Position) *Hover {
+	sym := doc.symbolAt(pos)
+	if sym == nil {
+		return nil
+	}
+	var text string
+	switch sym.Kind {
+	case scope.Func:
+		text = signature(sym.Func)
+	case scope.Param:
+		text = fmt.Sprintf("%s // parameter of %s", sym.Name, signature(sym.Func))
+	case scope.Module:
+		text = sym.Import.String()
+	default:
+		text = "var " + sym.Name
+	}
+	rng := doc.tokenRange(sym.Decl)
+	for _, ref := range sym.Refs {
+		if r := doc.tokenRange(ref); r.Start.Line == pos.Line && r.Start.Character <= pos.Character && pos.Character <= r.End.Character {
+			rng = r
+		}
+	}
+	return &Hover{MarkupContent{"markdown", "```lox\n" + text + "\n```"}, rng}
+}
+
+// Returns the keywords and names in scope at pos
+func completion(doc *document, pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	for _, kw := range lexer.Keywords() {
+		items = append(items, CompletionItem{Label: kw, Kind: CompletionKindKeyword})
+	}
+	for _, sym := range doc.info.VisibleAt(pos.Line, doc.offset(pos)) {
+		item := CompletionItem{Label: sym.Name, Kind: CompletionKindVariable, Detail: sym.Kind.String()}
+		switch sym.Kind {
+		case scope.Func:
+			item.Kind = CompletionKindFunction
+			item.Detail = signature(sym.Func)
+		case scope.Module:
+			item.Kind = CompletionKindModule
+			item.Detail = sym.Import.String()
+		}
+		items = append(items, item)
+	}
+	return items
+}
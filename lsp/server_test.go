@@ -0,0 +1,196 @@
+//go:build unit
+// +build unit
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *ResponseError  `json:"error"`
+}
+
+// Returns the response to request id
+func response(t *testing.T, out *bytes.Buffer, id int) testMessage {
+	for _, chunk := range bytes.Split(out.Bytes(), []byte("Content-Length: ")) {
+		i := bytes.Index(chunk, []byte("\r\n\r\n"))
+		if i < 0 {
+			continue
+		}
+		var msg testMessage
+		json.Unmarshal(chunk[i+4:], &msg)
+		if msg.ID != nil && *msg.ID == id {
+			return msg
+		}
+	}
+	t.Fatalf("No response to request %d", id)
+	return testMessage{}
+}
+
+const source = `var count = 1;
+fun add(a, b) {
+    return a + b;
+}
+print add(count, 2);
+`
+
+func open(text string) string {
+	item, _ := json.Marshal(map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///a.lox", "text": text, "version": 1},
+	})
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":%s}`, item)
+}
+
+func request(id int, method string, line, char int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":{"textDocument":{"uri":"file:///a.lox"},"position":{"line":%d,"character":%d},"context":{"includeDeclaration":true}}}`,
+		id, method, line, char)
+}
+
+func run(t *testing.T, requests ...string) *bytes.Buffer {
+	var in bytes.Buffer
+	requests = append(requests, `{"jsonrpc":"2.0","id":99,"method":"shutdown"}`, `{"jsonrpc":"2.0","method":"exit"}`)
+	for _, req := range requests {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(req), req)
+	}
+	var out bytes.Buffer
+	if err := Serve(&in, &out); err != nil {
+		t.Fatalf("Unexpected server error: %s", err)
+	}
+	return &out
+}
+
+func TestInitialize(t *testing.T) {
+	out := run(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	var res InitializeResult
+	json.Unmarshal(response(t, out, 1).Result, &res)
+	if !res.Capabilities.HoverProvider || res.Capabilities.TextDocumentSync != syncFull {
+		t.Fatalf("Wrong capabilities, got %+v", res.Capabilities)
+	}
+	if string(response(t, out, 99).Result) != "null" {
+		t.Fatalf("Expected null shutdown result")
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	out := run(t, open("var x = 1;\nprint x & 2;\nvar = 3;"))
+	if !bytes.Contains(out.Bytes(), []byte(`"method":"textDocument/publishDiagnostics"`)) {
+		t.Fatalf("Expected diagnostics to be published, got %s", out)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"message":"Unexpected character: '&'"`)) {
+		t.Fatalf("Expected lexer error diagnostic, got %s", out)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"start":{"line":1,"character":8}`)) {
+		t.Fatalf("Expected lexer error position, got %s", out)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`Expected next token to be IDENTIFIER`)) {
+		t.Fatalf("Expected parser error diagnostic, got %s", out)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	out := run(t, open(source), request(2, "textDocument/documentSymbol", 0, 0))
+	var syms []DocumentSymbol
+	json.Unmarshal(response(t, out, 2).Result, &syms)
+	if len(syms) != 2 || syms[0].Name != "count" || syms[1].Name != "add" {
+		t.Fatalf("Wrong document symbols, got %+v", syms)
+	}
+	if syms[1].Kind != SymbolKindFunction || syms[1].Detail != "fun add(a, b)" {
+		t.Fatalf("Wrong function symbol, got %+v", syms[1])
+	}
+	if syms[1].Range.End.Line != 3 {
+		t.Fatalf("Expected function range to end at its closing brace, got %+v", syms[1].Range)
+	}
+}
+
+func TestHover(t *testing.T) {
+	out := run(t, open(source), request(2, "textDocument/hover", 4, 7), request(3, "textDocument/hover", 2, 11))
+	var h Hover
+	json.Unmarshal(response(t, out, 2).Result, &h)
+	if h.Contents.Value != "```lox\nfun add(a, b)\n```" {
+		t.Fatalf("Wrong hover for function, got %q", h.Contents.Value)
+	}
+	json.Unmarshal(response(t, out, 3).Result, &h)
+	if h.Contents.Value != "```lox\na // parameter of fun add(a, b)\n```" {
+		t.Fatalf("Wrong hover for parameter, got %q", h.Contents.Value)
+	}
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	out := run(t, open(source), request(2, "textDocument/definition", 4, 11), request(3, "textDocument/references", 0, 5))
+	var loc Location
+	json.Unmarshal(response(t, out, 2).Result, &loc)
+	if loc.Range.Start != (Position{0, 4}) {
+		t.Fatalf("Wrong definition of count, got %+v", loc)
+	}
+	var refs []Location
+	json.Unmarshal(response(t, out, 3).Result, &refs)
+	if len(refs) != 2 || refs[1].Range.Start != (Position{4, 10}) {
+		t.Fatalf("Wrong references of count, got %+v", refs)
+	}
+}
+
+func TestPositionEncoding(t *testing.T) {
+	// x is at byte 22 of its line, but UTF-16 code unit 19
+	text := "var s = \"é😀\"; var x = s;\nprint x;\n"
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"general":{"positionEncodings":[%s]}}}}`
+	tests := []struct {
+		encodings string
+		expected  string
+		char      int
+	}{
+		{`"utf-16"`, encodingUTF16, 19},
+		{`"utf-8","utf-16"`, encodingUTF8, 22},
+	}
+	for _, tt := range tests {
+		out := run(t, fmt.Sprintf(initialize, tt.encodings), open(text),
+			request(2, "textDocument/definition", 1, 6), request(3, "textDocument/references", 0, tt.char))
+		var res InitializeResult
+		json.Unmarshal(response(t, out, 1).Result, &res)
+		if res.Capabilities.PositionEncoding != tt.expected {
+			t.Fatalf("Expected the %s encoding to be chosen from %s, got %q", tt.expected, tt.encodings, res.Capabilities.PositionEncoding)
+		}
+		var loc Location
+		json.Unmarshal(response(t, out, 2).Result, &loc)
+		if loc.Range != (Range{Position{0, tt.char}, Position{0, tt.char + 1}}) {
+			t.Fatalf("Wrong definition of x in %s, got %+v", tt.expected, loc.Range)
+		}
+		var refs []Location
+		json.Unmarshal(response(t, out, 3).Result, &refs)
+		if len(refs) != 2 || refs[1].Range.Start != (Position{1, 6}) {
+			t.Fatalf("Wrong references of x in %s, got %+v", tt.expected, refs)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	out := run(t, open(source), request(2, "textDocument/completion", 2, 4))
+	var items []CompletionItem
+	json.Unmarshal(response(t, out, 2).Result, &items)
+	labels := make(map[string]int)
+	for _, item := range items {
+		labels[item.Label] = item.Kind
+	}
+	for _, name := range []string{"a", "b", "add", "count", "while"} {
+		if _, found := labels[name]; !found {
+			t.Fatalf("Expected %q in completions, got %+v", name, items)
+		}
+	}
+	if labels["add"] != CompletionKindFunction || labels["while"] != CompletionKindKeyword {
+		t.Fatalf("Wrong completion kinds, got %+v", items)
+	}
+}
+
+func TestMethodNotFound(t *testing.T) {
+	out := run(t, `{"jsonrpc":"2.0","id":5,"method":"textDocument/formatting","params":{}}`)
+	if res := response(t, out, 5); res.Error == nil || res.Error.Code != codeMethodNotFound {
+		t.Fatalf("Expected method not found error, got %+v", res)
+	}
+}
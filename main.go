@@ -1,78 +1,236 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"github.com/fatih/color"
+	"golox/ast"
 	"golox/interp"
 	"golox/lexer"
 	"golox/parser"
-	"golox/report"
+	"golox/repl"
+	"golox/session"
+	"golox/stdlib"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Run interprets source code
-func Run(source string, intp *interp.Interpreter, show bool) {
+// Run interprets source code, writing errors to the interpreter's Stderr.
+// Returns if the source had syntax errors.
+func Run(source string, intp *interp.Interpreter, show bool) (hadError bool) {
 	scanner := lexer.NewLexer(source)
 	p := parser.New(&scanner)
 	prog := p.ParseProgram()
+	for _, e := range scanner.Errors() {
+		fmt.Fprintf(intp.Stderr, "%s %s\n", color.RedString("Error:"), e)
+	}
 	es := p.Errors()
 	if len(es) > 0 {
-		fmt.Printf("%s\n", color.MagentaString("%d parsing errors encountered.", len(es)))
+		fmt.Fprintf(intp.Stderr, "%s\n", color.MagentaString("%d parsing errors encountered.", len(es)))
 		for _, e := range p.Errors() {
-			fmt.Printf("%s %s\n", color.RedString("Error:"), e)
+			fmt.Fprintf(intp.Stderr, "%s %s\n", color.RedString("Error:"), e)
 		}
 	} else {
 		defer func() {
 			if err := recover(); err != nil {
-				fmt.Println(color.RedString("Runtime Error:"), err)
+				fmt.Fprintln(intp.Stderr, color.RedString("Runtime Error:"), err)
 			}
 		}()
 		if show {
-			fmt.Println(color.BlueString("%s", prog))
+			fmt.Fprintln(intp.Stdout, color.BlueString("%s", prog))
 			obj := intp.Eval(prog)
 			if obj != nil {
-				fmt.Println(" -> ", color.GreenString("%s", obj))
+				fmt.Fprintln(intp.Stdout, " -> ", color.GreenString("%s", obj))
 			}
 			intp.PrintEnv()
 		}
 	}
+	return len(scanner.Errors()) > 0 || len(es) > 0
+}
+
+// Parses the file at path, printing any errors.
+// Returns a nil program and the exit status if it could not be parsed.
+func parseFile(path string) (*ast.Program, int) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, 64
+	}
+	scanner := lexer.NewLexer(string(bytes))
+	p := parser.New(&scanner)
+	prog := p.ParseProgram()
+	for _, e := range scanner.Errors() {
+		fmt.Fprintf(os.Stderr, "%s %s\n", color.RedString("Error:"), e)
+	}
+	if es := p.Errors(); len(es) > 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", color.MagentaString("%d parsing errors encountered.", len(es)))
+		for _, e := range es {
+			fmt.Fprintf(os.Stderr, "%s %s\n", color.RedString("Error:"), e)
+		}
+		return nil, 65
+	}
+	return prog, 0
+}
+
+// Grants a group of builtins to scripts: without limits if given no value,
+// or only for the comma-separated paths, variable names or hosts given
+type allowFlag struct {
+	perms stdlib.Permissions
+	group stdlib.Group
+}
+
+func (f allowFlag) IsBoolFlag() bool { return true }
+func (f allowFlag) String() string {
+	if grant, granted := f.perms[f.group]; granted {
+		return strings.Join(grant.Only, ",")
+	}
+	return ""
+}
+func (f allowFlag) Set(s string) error {
+	switch s {
+	case "true":
+		f.perms[f.group] = stdlib.Grant{}
+	case "false":
+		delete(f.perms, f.group)
+	default:
+		grant := f.perms[f.group]
+		grant.Only = append(grant.Only, strings.Split(s, ",")...)
+		f.perms[f.group] = grant
+	}
+	return nil
+}
+
+// Usage of the flags of permissionFlags
+const permissionUsage = "[--allow-read[=paths]] [--allow-write[=paths]] [--allow-env[=names]] [--allow-net[=hosts]]"
+
+// Declares the flags granting builtins to scripts beyond the defaults of stdlib,
+// returning the permissions they set
+func permissionFlags(flags *flag.FlagSet) stdlib.Permissions {
+	perms := stdlib.Defaults()
+	flags.Var(allowFlag{perms, stdlib.Read}, "allow-read", "let scripts read files, only under the comma-separated `paths` if given")
+	flags.Var(allowFlag{perms, stdlib.Write}, "allow-write", "let scripts write files, only under the comma-separated `paths` if given")
+	flags.Var(allowFlag{perms, stdlib.Env}, "allow-env", "let scripts use environment variables, only those with the comma-separated `names` if given")
+	flags.Var(allowFlag{perms, stdlib.Net}, "allow-net", "let scripts make HTTP requests, only to the comma-separated `hosts` if given")
+	return perms
+}
+
+// Returns the directories listed in $LOXPATH, which programs import modules from
+// when they are not found relative to the importing file
+func importPath() []string {
+	return filepath.SplitList(os.Getenv("LOXPATH"))
 }
 
 // RunPrompt interprets lines in a REPL
 func RunPrompt() {
-	reader := bufio.NewReader(os.Stdin)
 	intp := interp.New()
-	fmt.Print("> ")
+	stdlib.Install(&intp, stdlib.Defaults())
+	stdlib.LimitImports(&intp, stdlib.Defaults())
+	intp.ImportPath = importPath()
+	reader := repl.NewReader(os.Stdin, os.Stdout, repl.NewCompleter(&intp))
 	for {
-		line, _, err := reader.ReadLine()
+		line, err := reader.ReadLine("> ")
 		if err != nil {
 			os.Exit(64)
 		}
-		Run(string(line), &intp, true)
-		fmt.Print("> ")
-		report.HadError = false
+		if strings.HasPrefix(strings.TrimSpace(line), ":") {
+			runCommand(line, &intp)
+			continue
+		}
+		Run(line, &intp, true)
 	}
 }
 
+// Runs a REPL command: ":save file" writes the globals of the session to the file,
+// and ":restore file" replaces the session with one saved to the file
+func runCommand(line string, intp *interp.Interpreter) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || (fields[0] != ":save" && fields[0] != ":restore") {
+		fmt.Fprintln(intp.Stderr, "Commands: :save file, :restore file")
+		return
+	}
+	if fields[0] == ":save" {
+		f, err := os.Create(fields[1])
+		if err != nil {
+			fmt.Fprintln(intp.Stderr, color.RedString("Error:"), err)
+			return
+		}
+		skipped, err := session.Save(f, intp)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(intp.Stderr, color.RedString("Error:"), err)
+		} else if len(skipped) > 0 {
+			fmt.Fprintln(intp.Stderr, "Not saved:", strings.Join(skipped, ", "))
+		}
+		return
+	}
+	f, err := os.Open(fields[1])
+	if err != nil {
+		fmt.Fprintln(intp.Stderr, color.RedString("Error:"), err)
+		return
+	}
+	defer f.Close()
+	// the current session is kept if the saved one cannot be restored
+	restored := interp.New()
+	restored.Prelude, restored.Stdin, restored.Stdout, restored.Stderr = intp.Prelude, intp.Stdin, intp.Stdout, intp.Stderr
+	restored.ImportPath, restored.NoImports, restored.AllowImport = intp.ImportPath, intp.NoImports, intp.AllowImport
+	if err := session.Restore(f, &restored); err != nil {
+		fmt.Fprintln(intp.Stderr, color.RedString("Error:"), err)
+		return
+	}
+	*intp = restored
+}
+
 // RunFile interprets a file
 func RunFile(path string) {
 	fmt.Println("Reading from file...")
 	intp := interp.New()
+	stdlib.Install(&intp, stdlib.Defaults())
+	stdlib.LimitImports(&intp, stdlib.Defaults())
+	intp.File, intp.ImportPath = path, importPath()
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(64)
 	}
-	Run(string(bytes), &intp, true)
-	if report.HadError {
+	if Run(string(bytes), &intp, true) {
 		os.Exit(65)
 	}
 }
 
+// Subcommands taking their own arguments, in the order of the usage
+var commands = []struct {
+	name  string
+	usage string
+	run   func(args []string) int // returns the exit status
+}{
+	{"run", runUsage, RunRun},
+	{"test", testUsage, RunTest},
+	{"lint", lintUsage, RunLint},
+	{"check", checkUsage, RunCheck},
+	{"fmt", fmtUsage, RunFmt},
+	{"ast", astUsage, RunAst},
+	{"tokens", tokensUsage, RunTokens},
+	{"lsp", lspUsage, RunLsp},
+	{"dap", dapUsage, RunDap},
+	{"debug", debugUsage, RunDebug},
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		for _, cmd := range commands {
+			if cmd.name == os.Args[1] {
+				os.Exit(cmd.run(os.Args[2:]))
+			}
+		}
+	}
 	if len(os.Args) > 2 {
 		fmt.Println("Usage: golox [script]")
+		for _, cmd := range commands {
+			fmt.Println("      ", cmd.usage)
+		}
 		os.Exit(64)
 	} else if len(os.Args) == 2 {
 		RunFile(os.Args[1])
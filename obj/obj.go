@@ -2,9 +2,11 @@ package obj
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/fatih/color"
 	"golox/ast"
+	"io"
 )
 
 type Env struct {
@@ -15,9 +17,10 @@ type Box struct {
 	Ref *Obj
 }
 
-func (e *Env) PrintColored() {
+// PrintColored writes every binding to w
+func (e *Env) PrintColored(w io.Writer) {
 	for key, elem := range e.Bindings {
-		fmt.Println(color.CyanString("%s", key), "=", color.YellowString("boxed"), *elem.Ref)
+		fmt.Fprintln(w, color.CyanString("%s", key), "=", color.YellowString("boxed"), *elem.Ref)
 	}
 }
 
@@ -49,8 +52,29 @@ const (
 	STR_OBJ
 	CLOSURE_OBJ
 	RET_VAL_OBJ
+	BUILTIN_OBJ
+	OBJECT_OBJ
 )
 
+var objTypeNames = [...]string{
+	NIL_OBJ:     "nil",
+	NUM_OBJ:     "number",
+	BOOL_OBJ:    "bool",
+	STR_OBJ:     "string",
+	CLOSURE_OBJ: "function",
+	RET_VAL_OBJ: "return value",
+	BUILTIN_OBJ: "function",
+	OBJECT_OBJ:  "object",
+}
+
+// String returns the name of the type as shown to Lox programmers
+func (t ObjType) String() string {
+	if int(t) < len(objTypeNames) {
+		return objTypeNames[t]
+	}
+	return fmt.Sprintf("ObjType(%d)", t)
+}
+
 type Nil struct{}
 
 func (n *Nil) Type() ObjType  { return NIL_OBJ }
@@ -60,6 +84,7 @@ type Closure struct {
 	EnvStack []Env
 	Params   []*ast.Identifier
 	Body     *ast.BlockStmt
+	Decl     *ast.FuncDeclStmt // the declaration the closure was created by, if any
 }
 
 func (c *Closure) Type() ObjType { return CLOSURE_OBJ }
@@ -77,6 +102,30 @@ func (c *Closure) String() string {
 	return out.String()
 }
 
+// Builtin is a function implemented in Go.
+// Fn panics with a string to report a runtime error at the call.
+type Builtin struct {
+	Name  string
+	Arity int // number of arguments, or -1 for any number
+	Fn    func(args []Obj) Obj
+	// FnContext, if set, is called instead of Fn with the context of the program,
+	// for builtins that wait and should stop early when it is cancelled
+	FnContext func(ctx context.Context, args []Obj) Obj
+}
+
+func (b *Builtin) Type() ObjType  { return BUILTIN_OBJ }
+func (b *Builtin) String() string { return "<builtin " + b.Name + ">" }
+
+// Object is a value with properties, such as a Go value exposed by the host program.
+// Get and Set panic with a string to report a runtime error at the property.
+type Object interface {
+	Obj
+	Get(name string) Obj // methods are returned as functions bound to the object
+	Set(name string, val Obj)
+	Properties() []string // names of the properties and methods, sorted
+	Equal(other Obj) bool
+}
+
 type RetVal struct {
 	Val Obj
 }
@@ -0,0 +1,37 @@
+//go:build unit
+// +build unit
+
+package parser
+
+import (
+	"golox/ast"
+	"golox/lexer"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzParseProgram(f *testing.F) {
+	paths, _ := filepath.Glob("../examples/*.lox")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(src))
+	}
+	f.Add("print 1 +;")
+	f.Add("fun (a) {}")
+	f.Add("1(2);")
+	f.Fuzz(func(t *testing.T, src string) {
+		l := lexer.NewLexer(src)
+		p := New(&l)
+		prog := p.ParseProgram()
+		// every part of the tree must be printable, even after errors
+		_ = prog.String()
+		ast.Inspect(prog, func(n ast.Node) bool {
+			_ = n.String()
+			return true
+		})
+	})
+}
@@ -13,14 +13,19 @@ type (
 )
 
 type ParserError struct {
-	msg string
+	Token token.Token // token the error was found at
+	msg   string
 }
 
+func (e ParserError) Error() string { return e.msg }
+
 type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
 	errors    []ParserError
+	comments  []token.Token
+	depth     int // of the blocks being parsed
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -60,6 +65,7 @@ func New(l *lexer.Lexer) *Parser {
 		token.AND:           p.parseInfixExpr,
 		token.OR:            p.parseInfixExpr,
 		token.LEFT_PAREN:    p.parseCallExpr,
+		token.DOT:           p.parseGetExpr,
 	}
 
 	return p
@@ -69,15 +75,30 @@ func (p *Parser) Errors() []ParserError {
 	return p.errors
 }
 
+// Comments returns the comments skipped over so far,
+// which are only produced if the lexer keeps comments
+func (p *Parser) Comments() []token.Token {
+	return p.comments
+}
+
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.ScanToken()
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, p.peekToken)
+		p.peekToken = p.l.ScanToken()
+	}
 }
 
 func (p *Parser) addError(t token.TokenType) {
 	msg := fmt.Sprintf("Expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, ParserError{msg: msg})
+	p.errors = append(p.errors, ParserError{p.peekToken, msg})
+}
+
+// Records an error at the current token
+func (p *Parser) errorf(format string, args ...interface{}) {
+	p.errors = append(p.errors, ParserError{p.curToken, fmt.Sprintf(format, args...)})
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -127,6 +148,10 @@ func (p *Parser) parseStatement() ast.Stmt {
 		return p.parseReturnStmt()
 	case token.PRINT:
 		return p.parsePrintStmt()
+	case token.DEBUGGER:
+		return p.parseDebuggerStmt()
+	case token.IMPORT:
+		return p.parseImportStmt()
 	default:
 		if p.curToken.Type == token.IDENTIFIER && p.peekToken.Type == token.EQUAL {
 			return p.parseAssignStmt()
@@ -145,24 +170,44 @@ func (p *Parser) matchPeek(t token.TokenType) bool {
 
 func (p *Parser) parseCallExpr(funcExpr ast.Expr) ast.Expr {
 	// because Lox doesn't have lambdas, we know the function call is a identifier
+	// or a method of an object
+	if get, isGet := funcExpr.(*ast.GetExpr); isGet {
+		args, ok := p.parseArgs()
+		if !ok {
+			return nil
+		}
+		return &ast.MethodCallExpr{Token: get.Name.Token, Object: get.Object, Name: get.Name, Args: args}
+	}
 	funcIdent, ok := funcExpr.(ast.Identifier)
 	if !ok {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected function identifier, got %t", funcExpr)})
+		if funcExpr == nil {
+			p.errorf("Expected function identifier before \"(\".")
+		} else {
+			p.errorf("Expected function identifier before \"(\", got %q", funcExpr.String())
+		}
 		p.advancePast(token.RIGHT_BRACE)
 		return nil
 	}
-	callExpr := &ast.CallExpr{Token: funcIdent.Token, Function: &funcIdent}
+	args, ok := p.parseArgs()
+	if !ok {
+		return nil
+	}
+	return &ast.CallExpr{Token: funcIdent.Token, Function: &funcIdent, Args: args}
+}
+
+// Parses the arguments of a call, starting at its LEFT_PAREN.
+// Returns false if they are invalid.
+func (p *Parser) parseArgs() ([]ast.Expr, bool) {
+	var args []ast.Expr
 	// we know curToken is LEFT_PAREN because the map infixParseFns
 	p.nextToken()
 	for p.curToken.Type != token.RIGHT_PAREN {
 		if p.curToken.Type == token.EOF {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected \")\", found end of file instead.")})
-			return nil
+			p.errorf("Expected \")\", found end of file instead.")
+			return nil, false
 		}
 		arg := p.parseExpr(LOWEST)
-		callExpr.Args = append(callExpr.Args,
+		args = append(args,
 			arg)
 
 		p.nextToken()
@@ -172,13 +217,22 @@ func (p *Parser) parseCallExpr(funcExpr ast.Expr) ast.Expr {
 		} else if p.curToken.Type == token.RIGHT_PAREN {
 			break
 		} else {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected comma separating argument identifiers, found %s", p.curToken.Type)})
+			p.errorf("Expected comma separating argument identifiers, found %s", p.curToken.Type)
 			p.advancePast(token.SEMICOLON)
-			return nil
+			return nil, false
 		}
 	}
-	return callExpr
+	return args, true
+}
+
+func (p *Parser) parseGetExpr(object ast.Expr) ast.Expr {
+	expr := &ast.GetExpr{Token: p.curToken, Object: object}
+	if !p.matchPeek(token.IDENTIFIER) {
+		p.addError(token.IDENTIFIER)
+		return nil
+	}
+	expr.Name = &ast.Identifier{Token: p.curToken}
+	return expr
 }
 
 func (p *Parser) parsePrintStmt() *ast.PrintStmt {
@@ -192,12 +246,60 @@ func (p *Parser) parsePrintStmt() *ast.PrintStmt {
 	return ps
 }
 
+func (p *Parser) parseDebuggerStmt() *ast.DebuggerStmt {
+	stmt := &ast.DebuggerStmt{Token: p.curToken}
+	if !p.matchPeek(token.SEMICOLON) {
+		p.addError(token.SEMICOLON)
+		return nil
+	}
+	return stmt
+}
+
+// Parses an import statement, which must be at the top level of the program.
+// "as" is only special after the path, so it can still name variables.
+func (p *Parser) parseImportStmt() *ast.ImportStmt {
+	stmt := &ast.ImportStmt{Token: p.curToken}
+	if p.depth > 0 {
+		p.errorf("Modules can only be imported at the top level of a file")
+		p.advancePast(token.SEMICOLON)
+		return nil
+	}
+	if !p.matchPeek(token.STRING) {
+		p.addError(token.STRING)
+		return nil
+	}
+	stmt.Path = p.curToken
+	if p.peekToken.Type == token.IDENTIFIER && p.peekToken.Lexeme == "as" {
+		p.nextToken()
+		if !p.matchPeek(token.IDENTIFIER) {
+			p.addError(token.IDENTIFIER)
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken}
+	} else if name := ast.ModuleName(stmt.ModulePath()); !isIdentifier(name) {
+		p.errorf("Cannot name the module %s after its file, name it with 'import %s as NAME;'", stmt.Path.Lexeme, stmt.Path.Lexeme)
+		p.advancePast(token.SEMICOLON)
+		return nil
+	}
+	if !p.matchPeek(token.SEMICOLON) {
+		p.addError(token.SEMICOLON)
+		return nil
+	}
+	return stmt
+}
+
+// Returns if name can name a variable
+func isIdentifier(name string) bool {
+	l := lexer.NewLexer(name)
+	toks := l.ScanTokens()
+	return len(toks) == 2 && toks[0].Type == token.IDENTIFIER && toks[0].Lexeme == name
+}
+
 func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 	stmt := &ast.FuncDeclStmt{Token: p.curToken}
 	p.nextToken()
 	if p.curToken.Type != token.IDENTIFIER {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected function name identifier, got %s", p.curToken.Type)})
+		p.errorf("Expected function name identifier, got %s", p.curToken.Type)
 		p.advancePast(token.RIGHT_BRACE)
 		return nil
 	}
@@ -205,8 +307,7 @@ func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 	stmt.Name = &ident
 	p.nextToken()
 	if p.curToken.Type != token.LEFT_PAREN {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected \"(\" after \"fun\".")})
+		p.errorf("Expected \"(\" after \"fun\".")
 		p.advancePast(token.RIGHT_BRACE)
 		return nil
 	}
@@ -215,8 +316,7 @@ func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 	exists := struct{}{}
 	for p.curToken.Type != token.RIGHT_PAREN {
 		if p.curToken.Type == token.EOF {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected \")\", found end of file instead.")})
+			p.errorf("Expected \")\", found end of file instead.")
 			return nil
 		}
 		// look for identifier
@@ -224,19 +324,23 @@ func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 			param := p.parseIdent().(ast.Identifier)
 			stmt.Params = append(stmt.Params,
 				&param)
+			typ, ok := p.parseAnnotation()
+			if !ok {
+				p.advancePast(token.RIGHT_BRACE)
+				return nil
+			}
+			stmt.ParamTypes = append(stmt.ParamTypes, typ)
 			_, dup := paramNames[param.String()]
 			if dup {
-				p.errors = append(p.errors,
-					ParserError{fmt.Sprintf(
-						"Found duplicate parameter identifier %q for function %q",
-						param, stmt.Name)})
+				p.errorf(
+					"Found duplicate parameter identifier %q for function %q",
+					param, stmt.Name)
 			} else {
 				paramNames[param.String()] = exists
 			}
 
 		} else {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected parameter identifier, found %s", p.curToken.Type)})
+			p.errorf("Expected parameter identifier, found %s", p.curToken.Type)
 			p.advancePast(token.RIGHT_BRACE)
 			return nil
 		}
@@ -248,12 +352,25 @@ func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 		} else if p.curToken.Type == token.RIGHT_PAREN {
 			break
 		} else {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected comma separating parameter identifiers, found %s", p.curToken.Type)})
+			p.errorf("Expected comma separating parameter identifiers, found %s", p.curToken.Type)
 			p.advancePast(token.RIGHT_BRACE)
 			return nil
 		}
 	}
+	// parameter types are only kept if some parameter is annotated
+	annotated := false
+	for _, typ := range stmt.ParamTypes {
+		annotated = annotated || typ != nil
+	}
+	if !annotated {
+		stmt.ParamTypes = nil
+	}
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		p.advancePast(token.RIGHT_BRACE)
+		return nil
+	}
+	stmt.ReturnType = typ
 	p.nextToken()
 
 	blockStmt := p.parseBlockStmt()
@@ -262,16 +379,29 @@ func (p *Parser) parseFuncDeclStmt() *ast.FuncDeclStmt {
 	return stmt
 }
 
+// Parses the type annotation following the current token, if there is one,
+// in the form ': TYPE'. Returns false if the annotation is invalid.
+func (p *Parser) parseAnnotation() (*ast.Identifier, bool) {
+	if !p.matchPeek(token.COLON) {
+		return nil, true
+	}
+	if !p.matchPeek(token.IDENTIFIER) {
+		p.addError(token.IDENTIFIER)
+		return nil, false
+	}
+	return &ast.Identifier{Token: p.curToken}, true
+}
+
 func (p *Parser) parseBlockStmt() *ast.BlockStmt {
 	if p.curToken.Type != token.LEFT_BRACE {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected opening brace, found %s", p.curToken.Type)})
+		p.errorf("Expected opening brace, found %s", p.curToken.Type)
 		return nil
 	}
-	p.nextToken()
-
-	block := &ast.BlockStmt{}
+	block := &ast.BlockStmt{Token: p.curToken}
 	block.Statements = []ast.Stmt{}
+	p.depth++
+	defer func() { p.depth-- }()
+	p.nextToken()
 
 	for p.curToken.Type != token.RIGHT_BRACE {
 		if p.curToken.Type == token.EOF {
@@ -284,6 +414,7 @@ func (p *Parser) parseBlockStmt() *ast.BlockStmt {
 		}
 		p.nextToken()
 	}
+	block.EndToken = p.curToken
 	return block
 }
 
@@ -294,6 +425,11 @@ func (p *Parser) parseVarStmt() *ast.VarStmt {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.curToken}
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+	stmt.Type = typ
 	if !p.matchPeek(token.EQUAL) {
 		p.addError(token.EQUAL)
 		return nil
@@ -338,9 +474,8 @@ func (p *Parser) parseAssignStmt() *ast.AssignStmt {
 	p.nextToken() // pass over the EQUAL token
 	stmt.Expr = p.parseExpr(LOWEST)
 	if p.peekToken.Type != token.SEMICOLON {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected ';' after %q at line %d:%d",
-				p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)})
+		p.errorf("Expected ';' after %q at line %d:%d",
+			p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)
 		p.advancePast(token.SEMICOLON)
 	} else {
 		p.nextToken()
@@ -363,14 +498,12 @@ func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 			p.advancePast(token.SEMICOLON)
 			postExprLine := p.curToken.Line
 			postExprLineOffset := p.curToken.LineOffset
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Invalid expression from %d:%d to %d:%d",
-					preExprLine, preExprLineOffset,
-					postExprLine, postExprLineOffset)})
+			p.errorf("Invalid expression from %d:%d to %d:%d",
+				preExprLine, preExprLineOffset,
+				postExprLine, postExprLineOffset)
 		} else if p.peekToken.Type != token.SEMICOLON {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected \";\" after %q at line %d:%d",
-					p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)})
+			p.errorf("Expected \";\" after %q at line %d:%d",
+				p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)
 			p.advancePast(token.SEMICOLON)
 		} else {
 			p.nextToken()
@@ -382,8 +515,7 @@ func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 func (p *Parser) advancePast(toktype token.TokenType) {
 	for p.peekToken.Type != token.SEMICOLON {
 		if p.peekToken.Type == token.EOF {
-			p.errors = append(p.errors,
-				ParserError{fmt.Sprintf("Expected to find %s before EOF", toktype)})
+			p.errorf("Expected to find %s before EOF", toktype)
 			break
 		}
 		p.nextToken()
@@ -403,7 +535,7 @@ const (
 	SUM         // + or -
 	PRODUCT     // * or /
 	PREFIX      // -X or !X
-	CALL        // myFunction(X)
+	CALL        // myFunction(X) or object.property
 )
 
 var precedences = map[token.TokenType]Prec{
@@ -420,6 +552,15 @@ var precedences = map[token.TokenType]Prec{
 	token.SLASH:         PRODUCT,
 	token.STAR:          PRODUCT,
 	token.LEFT_PAREN:    CALL,
+	token.DOT:           CALL,
+}
+
+// PrecedenceOf returns the binding power of an infix operator token
+func PrecedenceOf(t token.TokenType) Prec {
+	if pr, ok := precedences[t]; ok {
+		return pr
+	}
+	return LOWEST
 }
 
 func (p *Parser) peekPrec() Prec {
@@ -439,15 +580,17 @@ func (p *Parser) curPrec() Prec {
 // Parsing any type of expression should end on the last token of the expression
 // So peekToken after parsing all of a exprStmt should be semicolon
 
-func (p *Parser) parseExprStmt() *ast.ExprStmt {
+func (p *Parser) parseExprStmt() ast.Stmt {
 	stmt := &ast.ExprStmt{Token: p.curToken}
 	stmt.Expr = p.parseExpr(LOWEST)
+	if get, isGet := stmt.Expr.(*ast.GetExpr); isGet && p.peekToken.Type == token.EQUAL {
+		return p.parseSetStmt(stmt.Token, get)
+	}
 	// This no longer works correctly because function calls
 	// will both need a prefix function after the semicolon
 	if p.peekToken.Type != token.SEMICOLON {
-		p.errors = append(p.errors,
-			ParserError{fmt.Sprintf("Expected ';' after %q at line %d:%d",
-				p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)})
+		p.errorf("Expected ';' after %q at line %d:%d",
+			p.curToken.Lexeme, p.peekToken.Line, p.peekToken.LineOffset)
 		p.advancePast(token.SEMICOLON)
 	} else {
 		p.nextToken()
@@ -455,11 +598,25 @@ func (p *Parser) parseExprStmt() *ast.ExprStmt {
 	return stmt
 }
 
+// Parses the value assigned to the property get, ending on its semicolon
+func (p *Parser) parseSetStmt(start token.Token, get *ast.GetExpr) *ast.SetStmt {
+	stmt := &ast.SetStmt{Token: start, Object: get.Object, Name: get.Name}
+	p.nextToken()
+	p.nextToken() // pass over the EQUAL token
+	stmt.Value = p.parseExpr(LOWEST)
+	if !p.matchPeek(token.SEMICOLON) {
+		p.addError(token.SEMICOLON)
+		p.advancePast(token.SEMICOLON)
+		return nil
+	}
+	return stmt
+}
+
 func (p *Parser) parseExpr(prec Prec) ast.Expr {
 	prefix, found := p.prefixParseFns[p.curToken.Type]
 	if !found {
 		msg := fmt.Sprintf("no prefix parse function for %s found", p.curToken.Type)
-		p.errors = append(p.errors, ParserError{msg})
+		p.errors = append(p.errors, ParserError{p.curToken, msg})
 		return nil
 	}
 	leftExp := prefix()
@@ -467,7 +624,7 @@ func (p *Parser) parseExpr(prec Prec) ast.Expr {
 		infix, found := p.infixParseFns[p.peekToken.Type]
 		if !found {
 			msg := fmt.Sprintf("no infix parse function for %s found", p.curToken.Type)
-			p.errors = append(p.errors, ParserError{msg})
+			p.errors = append(p.errors, ParserError{p.curToken, msg})
 			return leftExp
 		}
 		p.nextToken()
@@ -518,7 +675,7 @@ func (p *Parser) parseGroupedExpr() ast.Expr {
 	exp := p.parseExpr(LOWEST)
 	if p.peekToken.Type != token.RIGHT_PAREN {
 		msg := fmt.Sprintf("expected ')', found %s", p.curToken.Type)
-		p.errors = append(p.errors, ParserError{msg})
+		p.errors = append(p.errors, ParserError{p.curToken, msg})
 		p.advancePast(token.RIGHT_PAREN)
 		p.nextToken()
 		return nil
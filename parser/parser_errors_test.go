@@ -272,6 +272,97 @@ func TestExprInvalid(t *testing.T) {
 		`"hey"`,
 		`x23 , 3`,
 		`x23 . 3`,
+		`(1)(2);`,
+		`(1 +)(2);`,
+		`print (;`,
+	}
+	for _, progStr := range progs {
+		assertInvalid(t, progStr)
+	}
+}
+func TestPropertyValid(t *testing.T) {
+	progs := []string{
+		`print request.method;`,
+		`request.headers.count;`,
+		`request.method = "GET";`,
+		`request.headers.count = 1 + 2;`,
+		`request.send();`,
+		`print request.header("Accept", 1 + 2).value;`,
+		`(request).method;`,
+		`f().method = nil;`,
+	}
+	for _, progStr := range progs {
+		assertNoErrors(t, progStr)
+	}
+}
+func TestPropertyInvalid(t *testing.T) {
+	progs := []string{
+		`request.;`,
+		`request.1;`,
+		`request..method;`,
+		`request.method = ;`,
+		`request.method = 1`,
+		`request.send(1,,2);`,
+		`1 + request = 2;`,
+	}
+	for _, progStr := range progs {
+		assertInvalid(t, progStr)
+	}
+}
+func TestTypeAnnotationInvalid(t *testing.T) {
+	progs := []string{
+		`var x: = 1;`,
+		`var x: 1 = 1;`,
+		`var x Num = 1;`,
+		`fun f(a:) {}`,
+		`fun f(a: Num b) {}`,
+		`fun f(): {}`,
+		`fun f() Num {}`,
+	}
+	for _, progStr := range progs {
+		assertInvalid(t, progStr)
+	}
+}
+func TestDebuggerValid(t *testing.T) {
+	progs := []string{
+		`debugger;`,
+		`fun f() { debugger; }`,
+		`if (x) { debugger; }`,
+	}
+	for _, progStr := range progs {
+		assertNoErrors(t, progStr)
+	}
+}
+func TestDebuggerInvalid(t *testing.T) {
+	progs := []string{
+		`debugger`,
+		`debugger x;`,
+		`debugger();`,
+	}
+	for _, progStr := range progs {
+		assertInvalid(t, progStr)
+	}
+}
+func TestImportValid(t *testing.T) {
+	progs := []string{
+		`import "lib/math.lox";`,
+		`import "lib/my-math.lox" as math;`,
+		`import "util" as util; var as = 1;`,
+	}
+	for _, progStr := range progs {
+		assertNoErrors(t, progStr)
+	}
+}
+func TestImportInvalid(t *testing.T) {
+	progs := []string{
+		`import "math.lox"`,
+		`import math;`,
+		`import "math.lox" as;`,
+		`import "math.lox" as "m";`,
+		`import "my-math.lox";`,
+		`import "lib/while.lox";`,
+		`fun f() { import "math.lox"; }`,
+		`if (x) { import "math.lox"; }`,
 	}
 	for _, progStr := range progs {
 		assertInvalid(t, progStr)
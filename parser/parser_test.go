@@ -619,6 +619,31 @@ func TestFunDeclStmt(t *testing.T) {
 	// TODO: test body content
 }
 
+func TestTypeAnnotations(t *testing.T) {
+	input := `var x: Num = 1; fun f(a: Str, b): Bool {}`
+	l := lexer.NewLexer(input)
+	p := New(&l)
+	program := p.ParseProgram()
+	assertNoParserErrors(t, p)
+
+	varStmt, ok := program.Statements[0].(*ast.VarStmt)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.VarStmt. got=%T",
+			program.Statements[0])
+	}
+	testIdentifier(t, *varStmt.Type, "Num")
+	funStmt, ok := program.Statements[1].(*ast.FuncDeclStmt)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.FuncDeclStmt. got=%T",
+			program.Statements[1])
+	}
+	testIdentifier(t, *funStmt.ParamType(0), "Str")
+	if funStmt.ParamType(1) != nil {
+		t.Errorf("Expected no type for parameter b, got=%s", funStmt.ParamType(1))
+	}
+	testIdentifier(t, *funStmt.ReturnType, "Bool")
+}
+
 func TestFunCallStmt(t *testing.T) {
 	input := `return FunctionName(x,10,z);`
 	l := lexer.NewLexer(input)
@@ -660,6 +685,50 @@ func TestFunCallStmt(t *testing.T) {
 	// TODO: test arg types
 }
 
+func TestPropertyExprs(t *testing.T) {
+	input := `print a.b.c; a.b.c = 1 + 2; a.b.m(x, 1);`
+	l := lexer.NewLexer(input)
+	p := New(&l)
+	program := p.ParseProgram()
+	assertNoParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements, got=%d\n",
+			len(program.Statements))
+	}
+	print := program.Statements[0].(*ast.PrintStmt)
+	get, ok := print.Expr.(*ast.GetExpr)
+	if !ok {
+		t.Fatalf("Printed value is not *ast.GetExpr. got=%T", print.Expr)
+	}
+	testIdentifier(t, *get.Name, "c")
+	if get.Object.String() != "a.b" {
+		t.Errorf("Object mismatch. Expected=a.b, got=%s", get.Object)
+	}
+
+	set, ok := program.Statements[1].(*ast.SetStmt)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *ast.SetStmt. got=%T", program.Statements[1])
+	}
+	testIdentifier(t, *set.Name, "c")
+	if set.Object.String() != "a.b" || set.Value.String() != "(1 + 2)" {
+		t.Errorf("Set mismatch. got=%s", set)
+	}
+	if set.Token.Lexeme != "a" {
+		t.Errorf("Expected the set statement to start at a, got=%q", set.Token.Lexeme)
+	}
+
+	expr := program.Statements[2].(*ast.ExprStmt)
+	call, ok := expr.Expr.(*ast.MethodCallExpr)
+	if !ok {
+		t.Fatalf("Expression is not *ast.MethodCallExpr. got=%T", expr.Expr)
+	}
+	testIdentifier(t, *call.Name, "m")
+	if call.Token.Lexeme != "m" || call.Object.String() != "a.b" || len(call.Args) != 2 {
+		t.Errorf("Method call mismatch. got=%s", call)
+	}
+}
+
 func TestAssignStmt(t *testing.T) {
 	input := `x = 100;`
 	l := lexer.NewLexer(input)
@@ -721,3 +790,36 @@ func TestAssignNilStmt(t *testing.T) {
 			stmt.Expr)
 	}
 }
+
+func TestImportStmt(t *testing.T) {
+	input := `import "lib/math.lox"; import "lib/my-math.lox" as m;`
+	l := lexer.NewLexer(input)
+	p := New(&l)
+	program := p.ParseProgram()
+	assertNoParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements, got=%d\n",
+			len(program.Statements))
+	}
+	tests := []struct {
+		path string
+		name string
+	}{
+		{"lib/math.lox", "math"},
+		{"lib/my-math.lox", "m"},
+	}
+	for i, tt := range tests {
+		stmt, ok := program.Statements[i].(*ast.ImportStmt)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.ImportStmt. got=%T", i, program.Statements[i])
+		}
+		if stmt.ModulePath() != tt.path {
+			t.Errorf("Path mismatch. Expected=%s, got=%s", tt.path, stmt.ModulePath())
+		}
+		testIdentifier(t, *stmt.Name(), tt.name)
+	}
+	if s := program.String(); s != input[:len(`import "lib/math.lox";`)]+`import "lib/my-math.lox" as m;` {
+		t.Errorf("String mismatch. got=%s", s)
+	}
+}
@@ -0,0 +1,180 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Field numbers of the messages in pprof's profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format read by pprof.
+// Samples count the statements started and the time spent in each stack of lines.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	t := newTables()
+	var out protoBuf
+	for _, vt := range [][2]string{{"statements", "count"}, {"time", "nanoseconds"}} {
+		out.message(profileSampleType, t.valueType(vt[0], vt[1]))
+	}
+	for _, s := range p.Samples() {
+		var msg, ids, values protoBuf
+		for _, loc := range s.Stack {
+			ids.varint(t.location(loc))
+		}
+		values.varint(uint64(s.Count))
+		values.varint(uint64(s.Time.Nanoseconds()))
+		msg.message(sampleLocationID, ids)
+		msg.message(sampleValue, values)
+		out.message(profileSample, msg)
+	}
+	for i, loc := range t.locations {
+		var msg, line protoBuf
+		msg.uint(locationID, uint64(i+1))
+		line.uint(lineFunctionID, t.function(loc.Func, loc.FuncLine))
+		line.uint(lineLine, uint64(loc.Line+1))
+		msg.message(locationLine, line)
+		out.message(profileLocation, msg)
+	}
+	for i, fn := range t.functions {
+		var msg protoBuf
+		msg.uint(functionID, uint64(i+1))
+		name := fn.Func
+		if name == "<script>" {
+			// pprof drops anything in angle brackets as C++ template arguments
+			name = "[script]"
+		}
+		msg.uint(functionName, t.str(name))
+		msg.uint(functionSystemName, t.str(fn.Func))
+		msg.uint(functionFilename, t.str(p.file))
+		msg.uint(functionStartLine, uint64(fn.FuncLine+1))
+		out.message(profileFunction, msg)
+	}
+	if !p.start.IsZero() {
+		out.uint(profileTimeNanos, uint64(p.start.UnixNano()))
+	}
+	out.uint(profileDurationNanos, uint64(p.duration.Nanoseconds()))
+	out.message(profilePeriodType, t.valueType("time", "nanoseconds"))
+	out.uint(profilePeriod, 1)
+	// every string is interned by now
+	for _, s := range t.strings {
+		out.message(profileStringTable, protoBuf(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(out); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Tables of the strings, locations and functions referenced by ID in a profile
+type tables struct {
+	strings     []string
+	stringIDs   map[string]uint64
+	locations   []Location
+	locationIDs map[Location]uint64
+	functions   []Location // only Func and FuncLine are set
+	functionIDs map[Location]uint64
+}
+
+func newTables() *tables {
+	return &tables{
+		strings:     []string{""}, // the empty string is always first
+		stringIDs:   map[string]uint64{"": 0},
+		locationIDs: make(map[Location]uint64),
+		functionIDs: make(map[Location]uint64),
+	}
+}
+
+// Returns the index of s in the string table
+func (t *tables) str(s string) uint64 {
+	id, found := t.stringIDs[s]
+	if !found {
+		id = uint64(len(t.strings))
+		t.strings = append(t.strings, s)
+		t.stringIDs[s] = id
+	}
+	return id
+}
+
+// Returns the ID of a location, counted from 1
+func (t *tables) location(loc Location) uint64 {
+	id, found := t.locationIDs[loc]
+	if !found {
+		t.locations = append(t.locations, loc)
+		id = uint64(len(t.locations))
+		t.locationIDs[loc] = id
+	}
+	return id
+}
+
+// Returns the ID of a function, counted from 1
+func (t *tables) function(name string, line int) uint64 {
+	fn := Location{Func: name, FuncLine: line}
+	id, found := t.functionIDs[fn]
+	if !found {
+		t.functions = append(t.functions, fn)
+		id = uint64(len(t.functions))
+		t.functionIDs[fn] = id
+	}
+	return id
+}
+
+func (t *tables) valueType(typ, unit string) protoBuf {
+	var msg protoBuf
+	msg.uint(valueTypeType, t.str(typ))
+	msg.uint(valueTypeUnit, t.str(unit))
+	return msg
+}
+
+// protoBuf is an encoded protocol buffer message
+type protoBuf []byte
+
+func (b *protoBuf) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+// Appends a varint field
+func (b *protoBuf) uint(field int, v uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(v)
+}
+
+// Appends a length-delimited field, such as a message, string or packed values
+func (b *protoBuf) message(field int, msg protoBuf) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(msg)))
+	*b = append(*b, msg...)
+}
@@ -0,0 +1,191 @@
+// Package profile measures the time spent in each Lox function and line
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"golox/ast"
+	"golox/obj"
+	"golox/token"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profiler is an interp.Tracer attributing the time between statements
+// to the stack of functions and lines being evaluated
+type Profiler struct {
+	file     string
+	now      func() time.Time
+	start    time.Time
+	last     time.Time
+	stack    []*frame
+	decls    map[string]int // declaration line of each function, by name
+	samples  map[string]*Sample
+	duration time.Duration
+}
+
+// A function call being evaluated
+type frame struct {
+	name  string
+	lines []int // lines of the statements being evaluated, innermost last
+}
+
+// Location is a line of a function
+type Location struct {
+	Func     string
+	FuncLine int // line the function is declared on, counted from 0
+	Line     int // counted from 0
+}
+
+// Sample is the cost of a call stack
+type Sample struct {
+	Stack []Location    // innermost first
+	Count int64         // statements started
+	Time  time.Duration // time spent
+}
+
+// New returns a Profiler for a program in file
+func New(file string) *Profiler {
+	return &Profiler{
+		file:    file,
+		now:     time.Now,
+		stack:   []*frame{{name: "<script>"}},
+		decls:   map[string]int{"<script>": 0},
+		samples: make(map[string]*Sample),
+	}
+}
+
+// Charges the time since the last event to the current stack
+func (p *Profiler) tick() {
+	now := p.now()
+	if p.start.IsZero() {
+		p.start, p.last = now, now
+	}
+	p.sample().Time += now.Sub(p.last)
+	p.duration += now.Sub(p.last)
+	p.last = now
+}
+
+// Returns the sample of the current stack
+func (p *Profiler) sample() *Sample {
+	stack := make([]Location, len(p.stack))
+	var key strings.Builder
+	for i, f := range p.stack {
+		loc := Location{Func: f.name, FuncLine: p.decls[f.name], Line: p.decls[f.name]}
+		if len(f.lines) > 0 {
+			loc.Line = f.lines[len(f.lines)-1]
+		}
+		stack[len(p.stack)-1-i] = loc
+		fmt.Fprintf(&key, "%s:%d:%d;", loc.Func, loc.FuncLine, loc.Line)
+	}
+	s, found := p.samples[key.String()]
+	if !found {
+		s = &Sample{Stack: stack}
+		p.samples[key.String()] = s
+	}
+	return s
+}
+
+func (p *Profiler) top() *frame {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *Profiler) Enter(node ast.Node) {
+	stmt, isStmt := node.(ast.Stmt)
+	if !isStmt {
+		return
+	}
+	p.tick()
+	line := ast.StmtToken(stmt).Line
+	if decl, isDecl := stmt.(*ast.FuncDeclStmt); isDecl && decl.Name != nil {
+		p.decls[decl.Name.String()] = line
+	}
+	top := p.top()
+	top.lines = append(top.lines, line)
+	p.sample().Count++
+}
+
+func (p *Profiler) Exit(node ast.Node, val obj.Obj) {
+	p.leave(node)
+}
+
+func (p *Profiler) Error(node ast.Node, err interface{}) {
+	p.leave(node)
+}
+
+// Ends a statement, whether or not it succeeded
+func (p *Profiler) leave(node ast.Node) {
+	if _, isStmt := node.(ast.Stmt); !isStmt {
+		return
+	}
+	p.tick()
+	top := p.top()
+	if len(top.lines) > 0 {
+		top.lines = top.lines[:len(top.lines)-1]
+	}
+}
+
+func (p *Profiler) Call(name string, call token.Token, args []obj.Obj) {
+	p.tick()
+	p.stack = append(p.stack, &frame{name: name})
+}
+
+func (p *Profiler) Return(name string, val obj.Obj) {
+	p.tick()
+	if len(p.stack) > 1 {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+}
+
+func (p *Profiler) Bind(name string, val obj.Obj)   {}
+func (p *Profiler) Assign(name string, val obj.Obj) {}
+
+// Samples returns the cost of every call stack, most expensive first
+func (p *Profiler) Samples() []*Sample {
+	samples := make([]*Sample, 0, len(p.samples))
+	for _, s := range p.samples {
+		if s.Count > 0 || s.Time > 0 {
+			samples = append(samples, s)
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Time != samples[j].Time {
+			return samples[i].Time > samples[j].Time
+		}
+		return stackString(samples[i].Stack, true) < stackString(samples[j].Stack, true)
+	})
+	return samples
+}
+
+// Returns a stack from the outermost frame, separated by semicolons
+func stackString(stack []Location, lines bool) string {
+	frames := make([]string, len(stack))
+	for i, loc := range stack {
+		frames[len(stack)-1-i] = loc.Func
+		if lines {
+			frames[len(stack)-1-i] += fmt.Sprintf(":%d", loc.Line+1)
+		}
+	}
+	return strings.Join(frames, ";")
+}
+
+// WriteCollapsed writes the time spent in every stack of functions, in nanoseconds,
+// in the collapsed format read by flamegraph tools: one "outer;inner value" line per stack
+func (p *Profiler) WriteCollapsed(w io.Writer) error {
+	totals := make(map[string]time.Duration)
+	for _, s := range p.Samples() {
+		totals[stackString(s.Stack, false)] += s.Time
+	}
+	stacks := make([]string, 0, len(totals))
+	for stack := range totals {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	bw := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		fmt.Fprintf(bw, "%s %d\n", stack, totals[stack].Nanoseconds())
+	}
+	return bw.Flush()
+}
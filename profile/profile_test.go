@@ -0,0 +1,111 @@
+//go:build unit
+// +build unit
+
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"golox/interp"
+	"golox/lexer"
+	"golox/parser"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+const source = `fun double(n) {
+    return n * 2;
+}
+var a = double(1);
+var b = double(a);
+`
+
+// Profiles source with a clock advancing a millisecond on every reading
+func profileSource(t *testing.T) *Profiler {
+	l := lexer.NewLexer(source)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	profiler := New("test.lox")
+	clock := time.Unix(0, 0)
+	profiler.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	intp := interp.New()
+	intp.Tracer = profiler
+	intp.Eval(prog)
+	return profiler
+}
+
+func TestSamples(t *testing.T) {
+	var stacks []string
+	counts := make(map[string]int64)
+	for _, s := range profileSource(t).Samples() {
+		stack := stackString(s.Stack, true)
+		stacks = append(stacks, stack)
+		counts[stack] = s.Count
+	}
+	for stack, want := range map[string]int64{
+		"<script>:1":          1,
+		"<script>:4":          1,
+		"<script>:5":          1,
+		"<script>:4;double:2": 1,
+		"<script>:5;double:2": 1,
+		"<script>:4;double:1": 0,
+	} {
+		got, found := counts[stack]
+		if !found {
+			t.Errorf("Expected a sample for %s, got %v", stack, stacks)
+		} else if got != want {
+			t.Errorf("Expected %d statements in %s, got %d", want, stack, got)
+		}
+	}
+}
+
+func TestWriteCollapsed(t *testing.T) {
+	var out bytes.Buffer
+	if err := profileSource(t).WriteCollapsed(&out); err != nil {
+		t.Fatal(err)
+	}
+	// each of the 14 events after the first ends a millisecond:
+	// 3 in each call of double, and the other 7 in the script
+	want := "<script> 7000000\n<script>;double 6000000\n"
+	if out.String() != want {
+		t.Fatalf("Expected collapsed stacks:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	var out bytes.Buffer
+	if err := profileSource(t).WritePprof(&out); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"test.lox", "double", "[script]", "statements", "nanoseconds"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Expected the profile to contain %q", s)
+		}
+	}
+}
+
+func TestProtoBuf(t *testing.T) {
+	var b protoBuf
+	b.uint(1, 300)
+	b.message(2, protoBuf("hi"))
+	want := []byte{0x08, 0xac, 0x02, 0x12, 0x02, 'h', 'i'}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("Expected %x, got %x", want, []byte(b))
+	}
+}
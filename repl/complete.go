@@ -0,0 +1,81 @@
+package repl
+
+import (
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"sort"
+	"strings"
+)
+
+// Completer returns the start of the word being completed at cursor in line,
+// along with every candidate that could replace it
+type Completer func(line []rune, cursor int) (start int, candidates []string)
+
+// NewCompleter returns a Completer offering keywords and every name
+// currently bound in the interpreter's environment stack,
+// or the properties of the object named before a dot
+func NewCompleter(intp *interp.Interpreter) Completer {
+	return func(line []rune, cursor int) (int, []string) {
+		start := wordStart(line, cursor)
+		word := string(line[start:cursor])
+		if start > 0 && line[start-1] == '.' {
+			name := string(line[wordStart(line, start-1) : start-1])
+			val, _ := intp.Lookup(name)
+			object, isObject := val.(obj.Object)
+			if !isObject {
+				return start, nil
+			}
+			return start, matching(word, object.Properties())
+		}
+		if word == "" {
+			return start, nil
+		}
+		return start, matching(word, lexer.Keywords(), intp.Names())
+	}
+}
+
+// Returns the names starting with word, sorted and without duplicates
+func matching(word string, sources ...[]string) []string {
+	var candidates []string
+	seen := make(map[string]struct{})
+	for _, src := range sources {
+		for _, name := range src {
+			if _, dup := seen[name]; dup || !strings.HasPrefix(name, word) {
+				continue
+			}
+			seen[name] = struct{}{}
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// Returns the index of the first char of the identifier ending at cursor
+func wordStart(line []rune, cursor int) int {
+	start := cursor
+	for start > 0 && isIdentChar(line[start-1]) {
+		start--
+	}
+	return start
+}
+
+// Returns if r can be part of an identifier
+func isIdentChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+// Returns the longest prefix shared by every candidate
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package repl
+
+import (
+	"golox/interp"
+	"golox/obj"
+	"reflect"
+	"testing"
+)
+
+func testComplete(t *testing.T, complete Completer, line string, expectedStart int, expected []string) {
+	runes := []rune(line)
+	start, candidates := complete(runes, len(runes))
+	if start != expectedStart {
+		t.Fatalf("Completing %q: expected word start %d, got %d", line, expectedStart, start)
+	}
+	if !reflect.DeepEqual(candidates, expected) {
+		t.Fatalf("Completing %q: expected candidates %q, got %q", line, expected, candidates)
+	}
+}
+
+func TestCompleteKeywords(t *testing.T) {
+	intp := interp.New()
+	complete := NewCompleter(&intp)
+	testComplete(t, complete, "wh", 0, []string{"while"})
+	testComplete(t, complete, "if x t", 5, []string{"this", "true"})
+	testComplete(t, complete, "", 0, nil)
+	testComplete(t, complete, "zzz", 0, nil)
+}
+
+func TestCompleteNames(t *testing.T) {
+	intp := interp.New()
+	intp.EnvStack[0].Bind("value", &obj.Num{Value: 1})
+	intp.EnvStack = append(intp.EnvStack, obj.NewEnv())
+	intp.EnvStack[1].Bind("vague", &obj.Num{Value: 2})
+	intp.EnvStack[1].Bind("value", &obj.Num{Value: 3})
+	complete := NewCompleter(&intp)
+	testComplete(t, complete, "print va", 6, []string{"vague", "value", "var"})
+	testComplete(t, complete, "x = vag", 4, []string{"vague"})
+	testComplete(t, complete, "x.va", 2, nil)
+	testComplete(t, complete, "value.va", 6, nil)
+}
+
+// An object with fixed properties
+type object []string
+
+func (o object) Type() obj.ObjType            { return obj.OBJECT_OBJ }
+func (o object) String() string               { return "object" }
+func (o object) Get(name string) obj.Obj      { return &obj.Nil{} }
+func (o object) Set(name string, val obj.Obj) {}
+func (o object) Properties() []string         { return o }
+func (o object) Equal(other obj.Obj) bool     { return false }
+
+func TestCompleteProperties(t *testing.T) {
+	intp := interp.New()
+	intp.EnvStack[0].Bind("req", object{"Header", "Method", "Retry"})
+	complete := NewCompleter(&intp)
+	testComplete(t, complete, "req.", 4, []string{"Header", "Method", "Retry"})
+	testComplete(t, complete, "print req.Me", 10, []string{"Method"})
+	testComplete(t, complete, "missing.Me", 8, nil)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		expected   string
+	}{
+		{[]string{"vague", "value", "var"}, "va"},
+		{[]string{"value", "values"}, "value"},
+		{[]string{"while"}, "while"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := commonPrefix(tt.candidates); got != tt.expected {
+			t.Fatalf("commonPrefix(%q): expected %q, got %q", tt.candidates, tt.expected, got)
+		}
+	}
+}
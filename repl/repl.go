@@ -0,0 +1,152 @@
+// Package repl implements line editing with tab completion for the REPL
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrInterrupt is returned by ReadLine when the user presses Ctrl-C
+var ErrInterrupt = errors.New("interrupted")
+
+// Control keys handled by the line editor
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = 9
+	keyNewline   = 10
+	keyEnter     = 13
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Reader reads lines from a terminal, completing words on Tab
+type Reader struct {
+	in       *os.File
+	out      io.Writer
+	buffered *bufio.Reader
+	complete Completer
+}
+
+// NewReader returns a Reader on in that echoes to out.
+// If in is not a terminal, lines are read as-is without editing.
+func NewReader(in *os.File, out io.Writer, complete Completer) *Reader {
+	return &Reader{in: in, out: out, buffered: bufio.NewReader(in), complete: complete}
+}
+
+// ReadLine prints prompt and returns the next line entered, without the newline
+func (r *Reader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	restore, err := makeRaw(r.in)
+	if err != nil {
+		// not a terminal, so fall back to plain line reading
+		line, err := r.buffered.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	defer restore()
+	return r.edit(prompt)
+}
+
+// Runs the line editor until the line is entered
+func (r *Reader) edit(prompt string) (string, error) {
+	var line []rune
+	cursor := 0
+	for {
+		c, _, err := r.buffered.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case keyEnter, keyNewline:
+			fmt.Fprint(r.out, "\r\n")
+			return string(line), nil
+		case keyCtrlC:
+			fmt.Fprint(r.out, "^C\r\n")
+			return "", ErrInterrupt
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(r.out, "\r\n")
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case keyTab:
+			line, cursor = r.tab(prompt, line, cursor)
+		case keyEscape:
+			cursor = r.escape(line, cursor)
+		default:
+			if c < ' ' {
+				continue
+			}
+			line = append(line[:cursor], append([]rune{c}, line[cursor:]...)...)
+			cursor++
+		}
+		r.redraw(prompt, line, cursor)
+	}
+}
+
+// Completes the word before the cursor, listing candidates when ambiguous
+func (r *Reader) tab(prompt string, line []rune, cursor int) ([]rune, int) {
+	if r.complete == nil {
+		return line, cursor
+	}
+	start, candidates := r.complete(line, cursor)
+	if len(candidates) == 0 {
+		return line, cursor
+	}
+	word := string(line[start:cursor])
+	insert := commonPrefix(candidates)[len(word):]
+	if len(candidates) == 1 {
+		insert = candidates[0][len(word):]
+	} else if insert == "" {
+		fmt.Fprintf(r.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+	rest := append([]rune(insert), line[cursor:]...)
+	line = append(line[:cursor], rest...)
+	return line, cursor + len([]rune(insert))
+}
+
+// Handles the arrow key escape sequences, ignoring any others
+func (r *Reader) escape(line []rune, cursor int) int {
+	if c, _, err := r.buffered.ReadRune(); err != nil || c != '[' {
+		return cursor
+	}
+	c, _, err := r.buffered.ReadRune()
+	if err != nil {
+		return cursor
+	}
+	switch c {
+	case 'C':
+		if cursor < len(line) {
+			cursor++
+		}
+	case 'D':
+		if cursor > 0 {
+			cursor--
+		}
+	case 'H':
+		cursor = 0
+	case 'F':
+		cursor = len(line)
+	}
+	return cursor
+}
+
+// Rewrites the current line and places the terminal cursor
+func (r *Reader) redraw(prompt string, line []rune, cursor int) {
+	fmt.Fprintf(r.out, "\r%s%s\x1b[K", prompt, string(line))
+	if back := len(line) - cursor; back > 0 {
+		fmt.Fprintf(r.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package repl
+
+import (
+	"errors"
+	"os"
+)
+
+// Line editing is only supported on unix terminals
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("line editing not supported on this platform")
+}
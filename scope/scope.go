@@ -0,0 +1,316 @@
+// Package scope resolves every identifier in a program to its declaration
+package scope
+
+import (
+	"golox/ast"
+	"golox/token"
+	"sort"
+)
+
+// Kind is the kind of declaration that introduced a Symbol
+type Kind uint8
+
+const (
+	Var Kind = iota
+	Func
+	Param
+	Module
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Func:
+		return "fun"
+	case Param:
+		return "param"
+	case Module:
+		return "module"
+	}
+	return "var"
+}
+
+// Symbol is a name declared by a var statement, a function declaration, a parameter or an import
+type Symbol struct {
+	Name   string
+	Kind   Kind
+	Decl   token.Token       // name token of the declaration
+	Func   *ast.FuncDeclStmt // declaring function, for Func and Param symbols
+	Import *ast.ImportStmt   // for Module symbols
+	Refs   []token.Token     // every use of the name resolved to this symbol
+	Scope  *Scope            // scope the symbol is declared in
+}
+
+// Scope is a region of the program where declared names are visible
+type Scope struct {
+	Parent   *Scope
+	Start    token.Token // first token of the region, zero for the global scope
+	End      token.Token // last token of the region, zero for the global scope
+	Symbols  []*Symbol
+	Children []*Scope
+}
+
+// Lookup returns the symbol name resolves to from this scope, or nil
+func (s *Scope) Lookup(name string) *Symbol {
+	for sc := s; sc != nil; sc = sc.Parent {
+		for i := len(sc.Symbols) - 1; i >= 0; i-- {
+			if sc.Symbols[i].Name == name {
+				return sc.Symbols[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Returns if the position is within the scope's region
+func (s *Scope) contains(line, offset int) bool {
+	if s.Parent == nil {
+		return true
+	}
+	return !before(line, offset, s.Start) && !after(line, offset, s.End)
+}
+
+// Info holds the result of resolving a program
+type Info struct {
+	Global     *Scope
+	Symbols    []*Symbol     // every symbol, in declaration order
+	Unresolved []token.Token // uses of names with no visible declaration
+}
+
+// Resolve declares and resolves every name in prog.
+// Names are visible from their declaration onwards, so functions
+// only see the outer names declared before them, as when interpreting.
+// Programs with parse errors are resolved as far as possible.
+func Resolve(prog *ast.Program) *Info {
+	r := &resolver{info: &Info{Global: &Scope{}}}
+	r.scope = r.info.Global
+	if prog != nil {
+		r.stmts(prog.Statements)
+	}
+	return r.info
+}
+
+type resolver struct {
+	info  *Info
+	scope *Scope
+}
+
+func (r *resolver) declare(ident *ast.Identifier, kind Kind, fn *ast.FuncDeclStmt) {
+	if ident == nil {
+		return
+	}
+	sym := &Symbol{Name: ident.Token.Lexeme, Kind: kind, Decl: ident.Token, Func: fn, Scope: r.scope}
+	r.scope.Symbols = append(r.scope.Symbols, sym)
+	r.info.Symbols = append(r.info.Symbols, sym)
+}
+
+func (r *resolver) use(tok token.Token) {
+	if sym := r.scope.Lookup(tok.Lexeme); sym != nil {
+		sym.Refs = append(sym.Refs, tok)
+	} else {
+		r.info.Unresolved = append(r.info.Unresolved, tok)
+	}
+}
+
+func (r *resolver) push(start token.Token, end token.Token) {
+	sc := &Scope{Parent: r.scope, Start: start, End: end}
+	r.scope.Children = append(r.scope.Children, sc)
+	r.scope = sc
+}
+
+func (r *resolver) pop() {
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) block(bs *ast.BlockStmt) {
+	if bs == nil {
+		return
+	}
+	r.push(bs.Token, bs.EndToken)
+	r.stmts(bs.Statements)
+	r.pop()
+}
+
+func (r *resolver) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.ExprStmt:
+		if stmt != nil {
+			r.expr(stmt.Expr)
+		}
+	case *ast.PrintStmt:
+		if stmt != nil {
+			r.expr(stmt.Expr)
+		}
+	case *ast.AssignStmt:
+		if stmt != nil {
+			r.expr(stmt.Expr)
+			r.use(stmt.Name.Token)
+		}
+	case *ast.SetStmt:
+		// property names are not variables
+		if stmt != nil {
+			r.expr(stmt.Object)
+			r.expr(stmt.Value)
+		}
+	case *ast.VarStmt:
+		if stmt != nil {
+			r.expr(stmt.Value)
+			r.declare(stmt.Name, Var, nil)
+		}
+	case *ast.ImportStmt:
+		if stmt != nil {
+			r.declare(stmt.Name(), Module, nil)
+			sym := r.scope.Symbols[len(r.scope.Symbols)-1]
+			sym.Import = stmt
+		}
+	case *ast.FuncDeclStmt:
+		if stmt == nil {
+			return
+		}
+		r.declare(stmt.Name, Func, stmt)
+		if stmt.Body == nil || stmt.Name == nil {
+			return
+		}
+		// parameters and the function's own name live in the call's scope,
+		// with the body as a nested block
+		self := r.scope.Symbols[len(r.scope.Symbols)-1]
+		r.push(stmt.Token, stmt.Body.EndToken)
+		r.scope.Symbols = append(r.scope.Symbols, self)
+		for _, param := range stmt.Params {
+			r.declare(param, Param, stmt)
+		}
+		r.block(stmt.Body)
+		r.pop()
+	case *ast.BlockStmt:
+		r.block(stmt)
+	case *ast.IfStmt:
+		if stmt != nil {
+			r.expr(stmt.Cond)
+			r.block(stmt.OnTrue)
+			r.block(stmt.OnFalse)
+		}
+	case *ast.WhileStmt:
+		if stmt != nil {
+			r.expr(stmt.Cond)
+			r.block(stmt.Body)
+		}
+	case *ast.ReturnStmt:
+		if stmt != nil {
+			r.expr(stmt.ReturnValue)
+		}
+	}
+}
+
+func (r *resolver) expr(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case ast.Identifier:
+		r.use(expr.Token)
+	case *ast.PrefixExpr:
+		if expr != nil {
+			r.expr(expr.Right)
+		}
+	case *ast.InfixExpr:
+		if expr != nil {
+			r.expr(expr.Left)
+			r.expr(expr.Right)
+		}
+	case *ast.CallExpr:
+		if expr == nil {
+			return
+		}
+		if expr.Function != nil {
+			r.use(expr.Function.Token)
+		}
+		for _, arg := range expr.Args {
+			r.expr(arg)
+		}
+	case *ast.GetExpr:
+		if expr != nil {
+			r.expr(expr.Object)
+		}
+	case *ast.MethodCallExpr:
+		if expr == nil {
+			return
+		}
+		r.expr(expr.Object)
+		for _, arg := range expr.Args {
+			r.expr(arg)
+		}
+	}
+}
+
+// SymbolAt returns the symbol whose declaration or use covers the position
+func (info *Info) SymbolAt(line, offset int) *Symbol {
+	for _, sym := range info.Symbols {
+		if covers(sym.Decl, line, offset) {
+			return sym
+		}
+		for _, ref := range sym.Refs {
+			if covers(ref, line, offset) {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// ScopeAt returns the innermost scope containing the position
+func (info *Info) ScopeAt(line, offset int) *Scope {
+	sc := info.Global
+	for {
+		inner := (*Scope)(nil)
+		for _, child := range sc.Children {
+			if child.contains(line, offset) {
+				inner = child
+			}
+		}
+		if inner == nil {
+			return sc
+		}
+		sc = inner
+	}
+}
+
+// VisibleAt returns every symbol that a name at the position could resolve to,
+// sorted by name with inner declarations shadowing outer ones
+func (info *Info) VisibleAt(line, offset int) []*Symbol {
+	seen := make(map[string]struct{})
+	var syms []*Symbol
+	for sc := info.ScopeAt(line, offset); sc != nil; sc = sc.Parent {
+		for i := len(sc.Symbols) - 1; i >= 0; i-- {
+			sym := sc.Symbols[i]
+			if _, dup := seen[sym.Name]; dup || !declaredBefore(sym, line, offset) {
+				continue
+			}
+			seen[sym.Name] = struct{}{}
+			syms = append(syms, sym)
+		}
+	}
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+	return syms
+}
+
+// Returns if the symbol's declaration starts before the position
+func declaredBefore(sym *Symbol, line, offset int) bool {
+	return sym.Decl.Line < line || sym.Decl.Line == line && sym.Decl.LineOffset < offset
+}
+
+// Returns if the position is before the start of tok
+func before(line, offset int, tok token.Token) bool {
+	return line < tok.Line || line == tok.Line && offset < tok.LineOffset
+}
+
+// Returns if the position is after the end of tok
+func after(line, offset int, tok token.Token) bool {
+	return line > tok.Line || line == tok.Line && offset > tok.LineOffset+len(tok.Lexeme)
+}
+
+// Returns if the position is within tok, including just past its end
+func covers(tok token.Token, line, offset int) bool {
+	return !before(line, offset, tok) && !after(line, offset, tok)
+}
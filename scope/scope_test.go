@@ -0,0 +1,98 @@
+//go:build unit
+// +build unit
+
+package scope
+
+import (
+	"golox/lexer"
+	"golox/parser"
+	"testing"
+)
+
+func resolveSource(t *testing.T, source string) *Info {
+	l := lexer.NewLexer(source)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	return Resolve(prog)
+}
+
+func TestResolveRefs(t *testing.T) {
+	info := resolveSource(t, `var x = 1;
+fun f(x) {
+    return x + f(x);
+}
+x = f(x);`)
+	if len(info.Symbols) != 3 {
+		t.Fatalf("Expected 3 symbols, got %d", len(info.Symbols))
+	}
+	global, fn, param := info.Symbols[0], info.Symbols[1], info.Symbols[2]
+	if global.Kind != Var || fn.Kind != Func || param.Kind != Param {
+		t.Fatalf("Wrong symbol kinds: %s %s %s", global.Kind, fn.Kind, param.Kind)
+	}
+	if len(global.Refs) != 2 {
+		t.Fatalf("Expected global x to have 2 refs, got %d", len(global.Refs))
+	}
+	if len(param.Refs) != 2 {
+		t.Fatalf("Expected param x to have 2 refs, got %d", len(param.Refs))
+	}
+	if len(fn.Refs) != 2 {
+		t.Fatalf("Expected f to have 2 refs including recursion, got %d", len(fn.Refs))
+	}
+	if sym := info.SymbolAt(2, 11); sym != param {
+		t.Fatalf("Expected symbol at 2:11 to be the parameter, got %v", sym)
+	}
+	if sym := info.SymbolAt(4, 0); sym != global {
+		t.Fatalf("Expected symbol at 4:0 to be the global, got %v", sym)
+	}
+}
+
+func TestResolveDeclarationOrder(t *testing.T) {
+	info := resolveSource(t, `fun f() { return y; }
+var y = 1;
+print z;`)
+	if len(info.Unresolved) != 2 {
+		t.Fatalf("Expected y in f and z to be unresolved, got %v", info.Unresolved)
+	}
+	if info.Unresolved[0].Lexeme != "y" || info.Unresolved[1].Lexeme != "z" {
+		t.Fatalf("Wrong unresolved names, got %v", info.Unresolved)
+	}
+}
+
+func TestResolveProperties(t *testing.T) {
+	info := resolveSource(t, `var x = 1;
+x.y = x.z;
+x.m(y);`)
+	if len(info.Symbols) != 1 || len(info.Symbols[0].Refs) != 3 {
+		t.Fatalf("Expected x to have 3 refs, got %v", info.Symbols)
+	}
+	if len(info.Unresolved) != 1 || info.Unresolved[0].Lexeme != "y" {
+		t.Fatalf("Expected only the argument y to be unresolved, got %v", info.Unresolved)
+	}
+}
+
+func TestVisibleAt(t *testing.T) {
+	info := resolveSource(t, `var a = 1;
+fun f(b) {
+    var c = 2;
+
+}
+var d = 3;`)
+	visible := info.VisibleAt(3, 4)
+	names := ""
+	for _, sym := range visible {
+		names += sym.Name
+	}
+	if names != "abcf" {
+		t.Fatalf("Expected a, b, c and f to be visible, got %q", names)
+	}
+	if n := len(info.VisibleAt(5, 10)); n != 3 {
+		t.Fatalf("Expected 3 globals visible at the end, got %d", n)
+	}
+}
+
+func TestResolvePartialProgram(t *testing.T) {
+	info := resolveSource(t, `var x = ; fun f( { if x { print y } }`)
+	if info == nil {
+		t.Fatalf("Expected info for a program with errors")
+	}
+}
@@ -0,0 +1,183 @@
+// Package session saves the global environment of an interpreter
+// as a Lox program declaring it again when run.
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golox/ast"
+	"golox/format"
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"golox/token"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Save writes the globals of intp to w as Lox declarations:
+// imported modules first, by their absolute paths,
+// then variables holding nil, numbers, booleans and strings,
+// then functions, declared from their source with its type annotations
+// after the functions they call.
+// Returns the names of the globals that cannot be saved, which are left out:
+// builtins, objects, functions using local variables of the function that created them,
+// strings containing quotes and numbers that are not finite.
+func Save(w io.Writer, intp *interp.Interpreter) (skipped []string, err error) {
+	globals := intp.EnvStack[0].Bindings
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var imports, src strings.Builder
+	funcs := make(map[string]*obj.Closure)
+	for _, name := range names {
+		val := *globals[name].Ref
+		if mod, isModule := val.(*interp.Module); isModule && !strings.Contains(mod.Path, `"`) {
+			fmt.Fprintf(&imports, "import \"%s\" as %s;\n", filepath.ToSlash(mod.Path), name)
+			continue
+		}
+		if closure, isClosure := val.(*obj.Closure); isClosure && !capturesLocals(closure) {
+			funcs[name] = closure
+			continue
+		}
+		lit, ok := literal(val)
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		fmt.Fprintf(&src, "var %s = %s;\n", name, lit)
+	}
+	for _, name := range ordered(funcs) {
+		closure := funcs[name]
+		if closure.Decl == nil {
+			fmt.Fprintf(&src, "fun %s%s\n", name, strings.TrimPrefix(closure.String(), "fun"))
+			continue
+		}
+		// the declaration keeps the type annotations, under the name of the global holding it
+		decl := *closure.Decl
+		decl.Name = &ast.Identifier{Token: token.Token{Type: token.IDENTIFIER, Lexeme: name}}
+		fmt.Fprintf(&src, "%s\n", decl.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Saved golox session, restored by running it\n")
+	if len(skipped) > 0 {
+		fmt.Fprintf(&out, "// Not saved: %s\n", strings.Join(skipped, ", "))
+	}
+	formatted, err := format.Source(imports.String() + src.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot save the session: %w", err)
+	}
+	out.WriteString(formatted)
+	_, err = w.Write(out.Bytes())
+	return skipped, err
+}
+
+// Restore runs the declarations written by Save in intp, which should not have declared them already
+func Restore(r io.Reader, intp *interp.Interpreter) (err error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	l := lexer.NewLexer(string(src))
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(l.Errors()) > 0 || len(p.Errors()) > 0 {
+		return errors.New("cannot restore the session: it is not a valid Lox program")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot restore the session: %v", r)
+		}
+	}()
+	intp.Eval(prog)
+	return nil
+}
+
+// Returns the Lox literal of a value, if it has one
+func literal(val obj.Obj) (string, bool) {
+	switch val := val.(type) {
+	case *obj.Nil:
+		return "nil", true
+	case *obj.Bool:
+		return strconv.FormatBool(val.Value), true
+	case *obj.Num:
+		if math.IsInf(val.Value, 0) || math.IsNaN(val.Value) {
+			return "", false
+		}
+		// the lexer reads neither exponents nor signs
+		lit := strconv.FormatFloat(math.Abs(val.Value), 'f', -1, 64)
+		if math.Signbit(val.Value) {
+			lit = "-" + lit
+		}
+		return lit, true
+	case *obj.Str:
+		if strings.Contains(val.Value, `"`) {
+			return "", false
+		}
+		return `"` + val.Value + `"`, true
+	}
+	return "", false
+}
+
+// Returns if a closure was created inside a function or block that declared variables,
+// which then cannot be declared again from its source
+func capturesLocals(closure *obj.Closure) bool {
+	for _, env := range closure.EnvStack[1:] {
+		if len(env.Bindings) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the names of funcs, each after the others it calls or uses as values
+func ordered(funcs map[string]*obj.Closure) []string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		// call targets are *ast.Identifier, while functions used as values are ast.Identifier
+		var calls []string
+		ast.Inspect(funcs[name].Body, func(node ast.Node) bool {
+			var id string
+			switch node := node.(type) {
+			case *ast.Identifier:
+				id = node.String()
+			case ast.Identifier:
+				id = node.String()
+			}
+			if _, isFunc := funcs[id]; isFunc {
+				calls = append(calls, id)
+			}
+			return true
+		})
+		for _, callee := range calls {
+			visit(callee)
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
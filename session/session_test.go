@@ -0,0 +1,182 @@
+//go:build unit
+// +build unit
+
+package session
+
+import (
+	"bytes"
+	"golox/interp"
+	"golox/lexer"
+	"golox/obj"
+	"golox/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Evaluates src in intp, failing the test on errors
+func eval(t *testing.T, intp *interp.Interpreter, src string) string {
+	t.Helper()
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(l.Errors()) > 0 || len(p.Errors()) > 0 {
+		t.Fatalf("Errors parsing %s: %v %v", src, l.Errors(), p.Errors())
+	}
+	if result := intp.Eval(prog); result != nil {
+		return result.String()
+	}
+	return ""
+}
+
+func TestRoundTrip(t *testing.T) {
+	intp := interp.New()
+	eval(t, &intp, `
+var n = -1.25;
+var big = 12345678901234567890;
+var s = "two
+lines";
+var yes = true;
+var none = nil;
+fun square(x) { return x * x; }
+fun sumSquares(a, b) { return square(a) + square(b); }
+fun fib(n) { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); }
+fun zhelper(n) { return n + 1; }
+fun apply(f, n) { return f(n); }
+fun caller(n) { return apply(zhelper, n); }
+`)
+	var saved bytes.Buffer
+	skipped, err := Save(&saved, &intp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Fatalf("Expected every global to be saved, skipped %v", skipped)
+	}
+	// square is declared before sumSquares, which calls it
+	src := saved.String()
+	if strings.Index(src, "fun square") > strings.Index(src, "fun sumSquares") {
+		t.Errorf("Expected square before sumSquares in:\n%s", src)
+	}
+	// and zhelper before caller, which passes it to apply
+	if strings.Index(src, "fun zhelper") > strings.Index(src, "fun caller") {
+		t.Errorf("Expected zhelper before caller in:\n%s", src)
+	}
+
+	restored := interp.New()
+	if err := Restore(&saved, &restored); err != nil {
+		t.Fatalf("%v in:\n%s", err, src)
+	}
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`return n;`, "-1.250000"},
+		{`return big == 12345678901234567890;`, "true"},
+		{`return s;`, "two\nlines"},
+		{`return yes;`, "true"},
+		{`return none;`, "nil"},
+		{`return sumSquares(1, 2);`, "5.000000"},
+		{`return fib(10);`, "55.000000"},
+		{`return caller(3);`, "4.000000"},
+	}
+	for _, tt := range tests {
+		if got := eval(t, &restored, tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	intp := interp.New()
+	eval(t, &intp, `fun add(a: Num, b: Num): Num { return a + b; }
+var plus = add;`)
+	var saved bytes.Buffer
+	if _, err := Save(&saved, &intp); err != nil {
+		t.Fatal(err)
+	}
+	src := saved.String()
+	for _, decl := range []string{"fun add(a: Num, b: Num): Num {", "fun plus(a: Num, b: Num): Num {"} {
+		if !strings.Contains(src, decl) {
+			t.Errorf("Expected %q in:\n%s", decl, src)
+		}
+	}
+
+	restored := interp.New()
+	if err := Restore(&saved, &restored); err != nil {
+		t.Fatalf("%v in:\n%s", err, src)
+	}
+	closure := (*restored.EnvStack[0].Bindings["add"].Ref).(*obj.Closure)
+	if closure.Decl == nil || closure.Decl.ReturnType.String() != "Num" || closure.Decl.ParamType(1).String() != "Num" {
+		t.Fatalf("Expected the restored add to keep its annotations, got %+v", closure.Decl)
+	}
+	if got := eval(t, &restored, `return plus(1, 2);`); got != "3.000000" {
+		t.Errorf("Expected plus(1, 2) to give 3, got %s", got)
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	intp := interp.New()
+	eval(t, &intp, `
+fun makeCounter() { var i = 0; fun count() { i = i + 1; return i; } return count; }
+var counter = makeCounter();
+var inf = 1 / 0;
+var kept = 1;
+`)
+	// Lox strings have no escapes, so quotes can only come from Go
+	intp.EnvStack[0].Bind("quoted", &obj.Str{Value: `say "hi"`})
+	var saved bytes.Buffer
+	skipped, err := Save(&saved, &intp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"counter", "inf", "quoted"}; !reflect.DeepEqual(skipped, expected) {
+		t.Fatalf("Expected %v to be skipped, got %v", expected, skipped)
+	}
+	if !strings.Contains(saved.String(), "// Not saved: counter, inf, quoted\n") {
+		t.Errorf("Expected the skipped globals to be listed in:\n%s", saved.String())
+	}
+	restored := interp.New()
+	if err := Restore(&saved, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := eval(t, &restored, `var c = makeCounter(); return kept + c();`); got != "2.000000" {
+		t.Errorf("Expected 2, got %s", got)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	intp := interp.New()
+	if err := Restore(strings.NewReader(`var = ;`), &intp); err == nil {
+		t.Error("Expected an error restoring an invalid program")
+	}
+	eval(t, &intp, `var x = 1;`)
+	err := Restore(strings.NewReader(`var x = 2;`), &intp)
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot restore the session: ") {
+		t.Errorf("Expected an error redeclaring a global, got %v", err)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "math.lox"), []byte("var pi = 3;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	intp := interp.New()
+	intp.File = filepath.Join(dir, "main.lox")
+	eval(t, &intp, `import "math.lox" as m;`)
+	var saved bytes.Buffer
+	if skipped, err := Save(&saved, &intp); err != nil || len(skipped) != 0 {
+		t.Fatalf("Expected the module to be saved, skipped %v, %v", skipped, err)
+	}
+	// modules are imported again by their absolute path
+	restored := interp.New()
+	if err := Restore(&saved, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := eval(t, &restored, `return m.pi;`); got != "3.000000" {
+		t.Errorf("Expected 3, got %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+package stdlib
+
+import (
+	"errors"
+	"fmt"
+	"golox/obj"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var builtins = []builtin{
+	// Core
+	{Core, "len", 1, func(c *call) obj.Obj {
+		return &obj.Num{Value: float64(utf8.RuneCountInString(c.str(0)))}
+	}},
+	{Core, "str", 1, func(c *call) obj.Obj {
+		if s, isStr := c.args[0].(*obj.Str); isStr {
+			return s
+		}
+		return &obj.Str{Value: c.args[0].String()}
+	}},
+	{Core, "num", 1, func(c *call) obj.Obj {
+		n, err := strconv.ParseFloat(strings.TrimSpace(c.str(0)), 64)
+		if err != nil {
+			return &obj.Nil{}
+		}
+		return &obj.Num{Value: n}
+	}},
+	{Core, "type", 1, func(c *call) obj.Obj {
+		return &obj.Str{Value: c.args[0].Type().String()}
+	}},
+
+	// Math
+	{Math, "sqrt", 1, mathFn(math.Sqrt)},
+	{Math, "floor", 1, mathFn(math.Floor)},
+	{Math, "ceil", 1, mathFn(math.Ceil)},
+	{Math, "abs", 1, mathFn(math.Abs)},
+	{Math, "pow", 2, func(c *call) obj.Obj {
+		return &obj.Num{Value: math.Pow(c.num(0), c.num(1))}
+	}},
+	{Math, "min", 2, func(c *call) obj.Obj {
+		return &obj.Num{Value: math.Min(c.num(0), c.num(1))}
+	}},
+	{Math, "max", 2, func(c *call) obj.Obj {
+		return &obj.Num{Value: math.Max(c.num(0), c.num(1))}
+	}},
+	{Math, "random", 0, func(c *call) obj.Obj {
+		return &obj.Num{Value: rand.Float64()}
+	}},
+
+	// Time
+	{Time, "clock", 0, func(c *call) obj.Obj {
+		return &obj.Num{Value: float64(time.Now().UnixNano()) / float64(time.Second)}
+	}},
+	{Time, "sleep", 1, func(c *call) obj.Obj {
+		timer := time.NewTimer(time.Duration(c.num(0) * float64(time.Second)))
+		defer timer.Stop()
+		// a cancelled program stops at its next step
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+		}
+		return &obj.Nil{}
+	}},
+
+	// Read
+	{Read, "readFile", 1, func(c *call) obj.Obj {
+		b, err := os.ReadFile(c.path(0))
+		if err != nil {
+			c.fail(err)
+		}
+		return &obj.Str{Value: string(b)}
+	}},
+	{Read, "fileExists", 1, func(c *call) obj.Obj {
+		_, err := os.Stat(c.path(0))
+		return &obj.Bool{Value: err == nil}
+	}},
+
+	// Write
+	{Write, "writeFile", 2, func(c *call) obj.Obj {
+		if err := os.WriteFile(c.path(0), []byte(c.str(1)), 0644); err != nil {
+			c.fail(err)
+		}
+		return &obj.Nil{}
+	}},
+	{Write, "appendFile", 2, func(c *call) obj.Obj {
+		f, err := os.OpenFile(c.path(0), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			c.fail(err)
+		}
+		_, err = f.WriteString(c.str(1))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			c.fail(err)
+		}
+		return &obj.Nil{}
+	}},
+	{Write, "removeFile", 1, func(c *call) obj.Obj {
+		if err := os.Remove(c.path(0)); err != nil {
+			c.fail(err)
+		}
+		return &obj.Nil{}
+	}},
+
+	// Env
+	{Env, "getenv", 1, func(c *call) obj.Obj {
+		c.allow(c.str(0))
+		val, found := os.LookupEnv(c.str(0))
+		if !found {
+			return &obj.Nil{}
+		}
+		return &obj.Str{Value: val}
+	}},
+	{Env, "setenv", 2, func(c *call) obj.Obj {
+		c.allow(c.str(0))
+		if err := os.Setenv(c.str(0), c.str(1)); err != nil {
+			c.fail(err)
+		}
+		return &obj.Nil{}
+	}},
+
+	// Net
+	{Net, "httpGet", 1, func(c *call) obj.Obj {
+		u, err := url.Parse(c.str(0))
+		if err != nil {
+			c.fail(err)
+		}
+		if denied := c.checkURL(u); denied != "" {
+			panic(denied)
+		}
+		req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			c.fail(err)
+		}
+		// redirects must be to URLs the script could get itself
+		var denied string
+		client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if denied = c.checkURL(req.URL); denied != "" {
+				return errors.New(denied)
+			}
+			return nil
+		}}
+		resp, err := client.Do(req)
+		if denied != "" {
+			panic(denied)
+		}
+		if err != nil {
+			c.fail(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			panic(fmt.Sprintf("%s: %s responded %s.", c.name, resp.Request.URL, resp.Status))
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			c.fail(err)
+		}
+		return &obj.Str{Value: string(body)}
+	}},
+}
+
+// Returns a builtin applying f to a number
+func mathFn(f func(float64) float64) func(c *call) obj.Obj {
+	return func(c *call) obj.Obj {
+		return &obj.Num{Value: f(c.num(0))}
+	}
+}
+
+// Returns the error of getting u, if its scheme is not HTTP or the grant does not allow its host,
+// given with or without its port, or else ""
+func (c *call) checkURL(u *url.URL) string {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("%s: unsupported URL scheme %q.", c.name, u.Scheme)
+	}
+	if len(c.grant.Only) == 0 {
+		return ""
+	}
+	for _, allowed := range c.grant.Only {
+		if allowed == u.Host || allowed == u.Hostname() {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Permission denied: %s cannot access %q.", c.name, u.Hostname())
+}
@@ -0,0 +1,224 @@
+// Package stdlib provides the builtin functions of Lox in groups,
+// each needing a capability that the host grants to scripts.
+// The builtins of groups that are not granted are still declared,
+// so scripts calling them fail with a permission error rather than an undefined variable.
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"golox/interp"
+	"golox/obj"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Group is a set of builtins needing the same capability
+type Group int
+
+const (
+	Core  Group = iota // pure functions on values: len, str, num, type
+	Math               // sqrt, floor, ceil, abs, pow, min, max, random
+	Time               // clock, sleep
+	Read               // readFile, fileExists
+	Write              // writeFile, appendFile, removeFile
+	Env                // getenv, setenv
+	Net                // httpGet
+)
+
+var groupNames = [...]string{
+	Core:  "core",
+	Math:  "math",
+	Time:  "time",
+	Read:  "read",
+	Write: "write",
+	Env:   "env",
+	Net:   "net",
+}
+
+// String returns the name of the capability the group needs
+func (g Group) String() string {
+	if int(g) < len(groupNames) {
+		return groupNames[g]
+	}
+	return fmt.Sprintf("Group(%d)", g)
+}
+
+// Grant allows scripts the builtins of a group. If Only is empty, they can be used without limits.
+// Otherwise the builtins of Read and Write may only use files under the paths in Only,
+// those of Env only the variables named, and those of Net only the hosts.
+type Grant struct {
+	Only []string
+}
+
+// Permissions are the groups granted to scripts, the others being denied
+type Permissions map[Group]Grant
+
+// Defaults returns the permissions of the groups that cannot reach outside the interpreter:
+// Core, Math and Time
+func Defaults() Permissions {
+	return Permissions{Core: {}, Math: {}, Time: {}}
+}
+
+// All returns permissions granting every group without limits
+func All() Permissions {
+	perms := Permissions{}
+	for g := range groupNames {
+		perms[Group(g)] = Grant{}
+	}
+	return perms
+}
+
+// A builtin function of a group
+type builtin struct {
+	group Group
+	name  string
+	arity int
+	fn    func(c *call) obj.Obj
+}
+
+// A call to a builtin
+type call struct {
+	name  string
+	args  []obj.Obj
+	ctx   context.Context // of the program calling it
+	grant Grant
+}
+
+// Install declares every builtin in the prelude of intp, where programs can shadow them.
+// The builtins of the groups perms does not grant fail with a permission error when called.
+func Install(intp *interp.Interpreter, perms Permissions) {
+	if intp.Prelude.Bindings == nil {
+		intp.Prelude = obj.NewEnv()
+	}
+	for name, box := range Prelude(perms).Bindings {
+		intp.Prelude.Bind(name, *box.Ref)
+	}
+}
+
+// LimitImports lets intp import modules only if perms grants Read, as imports read files,
+// and then only the files under the paths of the grant, as for readFile
+func LimitImports(intp *interp.Interpreter, perms Permissions) {
+	grant, granted := perms[Read]
+	intp.NoImports = !granted
+	intp.AllowImport = func(path string) error {
+		if !grant.allowsPath(path) {
+			return fmt.Errorf("Permission denied: import cannot access %q.", path)
+		}
+		return nil
+	}
+}
+
+// Prelude returns an environment declaring every builtin, as Install does.
+// Interpreters running in parallel can share it as their Prelude, as long as none modifies it.
+func Prelude(perms Permissions) obj.Env {
+	env := obj.NewEnv()
+	for _, b := range builtins {
+		b := b
+		grant, granted := perms[b.group]
+		env.Bind(b.name, &obj.Builtin{Name: b.name, Arity: b.arity, FnContext: func(ctx context.Context, args []obj.Obj) obj.Obj {
+			if !granted {
+				panic(fmt.Sprintf("Permission denied: %s needs the %q capability.", b.name, b.group))
+			}
+			return b.fn(&call{name: b.name, args: args, ctx: ctx, grant: grant})
+		}})
+	}
+	return env
+}
+
+// Names returns the names of every builtin, sorted
+func Names() []string {
+	names := make([]string, len(builtins))
+	for i, b := range builtins {
+		names[i] = b.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Panics with a permission error unless the grant allows what, a variable name or host
+func (c *call) allow(what string) {
+	if len(c.grant.Only) == 0 {
+		return
+	}
+	for _, allowed := range c.grant.Only {
+		if what == allowed {
+			return
+		}
+	}
+	c.deny(what)
+}
+
+func (c *call) deny(what string) {
+	panic(fmt.Sprintf("Permission denied: %s cannot access %q.", c.name, what))
+}
+
+// Returns the argument as an absolute path, panicking with a permission error
+// unless it is under one of the paths the grant allows
+func (c *call) path(i int) string {
+	path := c.str(i)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		c.fail(err)
+	}
+	if !c.grant.allowsPath(abs) {
+		c.deny(path)
+	}
+	return abs
+}
+
+// Returns if the absolute path is under one of the paths in Only, or Only is empty
+func (g Grant) allowsPath(abs string) bool {
+	if len(g.Only) == 0 {
+		return true
+	}
+	real := resolve(abs)
+	for _, dir := range g.Only {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolve(dir), real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolves the symbolic links in path, or in its directory if it does not exist yet
+func resolve(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+// Panics with the error of a Go function as a runtime error
+func (c *call) fail(err error) {
+	panic(fmt.Sprintf("%s: %s.", c.name, err))
+}
+
+func (c *call) str(i int) string {
+	s, isStr := c.args[i].(*obj.Str)
+	if !isStr {
+		c.wrongType(i, "a string")
+	}
+	return s.Value
+}
+
+func (c *call) num(i int) float64 {
+	n, isNum := c.args[i].(*obj.Num)
+	if !isNum {
+		c.wrongType(i, "a number")
+	}
+	return n.Value
+}
+
+func (c *call) wrongType(i int, expected string) {
+	panic(fmt.Sprintf("Argument %d of %q must be %s, got %s.", i+1, c.name, expected, c.args[i].Type()))
+}
@@ -0,0 +1,236 @@
+//go:build unit
+// +build unit
+
+package stdlib
+
+import (
+	"context"
+	"fmt"
+	"golox/interp"
+	"golox/lexer"
+	"golox/parser"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Evaluates src with the permissions, returning the value of its top-level return
+// or the message of its runtime error
+func eval(t *testing.T, perms Permissions, src string) (result string) {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	intp := interp.New()
+	Install(&intp, perms)
+	defer func() {
+		if r := recover(); r != nil {
+			if rerr, isRuntime := r.(*interp.RuntimeError); isRuntime {
+				result = rerr.Msg
+			} else {
+				result = fmt.Sprint(r)
+			}
+		}
+	}()
+	return intp.Eval(prog).String()
+}
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`return len("héllo");`, "5.000000"},
+		{`return str(2);`, "2.000000"},
+		{`return num(" 1.5 ");`, "1.500000"},
+		{`return num("one");`, "nil"},
+		{`return type(len);`, "function"},
+		{`return sqrt(16) + floor(1.5) + ceil(1.5) + abs(-1);`, "8.000000"},
+		{`return pow(2, 10) + min(1, 2) + max(1, 2);`, "1027.000000"},
+		{`var r = random(); return r >= 0 and r < 1;`, "true"},
+		{`return clock() > 0;`, "true"},
+		{`return len(1);`, `Argument 1 of "len" must be a string, got number.`},
+		// programs can shadow builtins, but not assign them
+		{`fun pow(a, b) { return a; } return pow(2, 10);`, "2.000000"},
+		{`sqrt = 1;`, `Cannot assign to builtin "sqrt". Use "var sqrt = ...;" to declare a variable instead.`},
+		{`return readFile("x");`, `Permission denied: readFile needs the "read" capability.`},
+		{`writeFile("x", "y");`, `Permission denied: writeFile needs the "write" capability.`},
+		{`return getenv("HOME");`, `Permission denied: getenv needs the "env" capability.`},
+		{`return httpGet("http://example.com");`, `Permission denied: httpGet needs the "net" capability.`},
+	}
+	for _, tt := range tests {
+		if got := eval(t, Defaults(), tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("hidden"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a link from inside the allowed directory to a file outside of it
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(data, "link")); err != nil {
+		t.Fatal(err)
+	}
+	perms := Permissions{Read: {Only: []string{data}}, Write: {Only: []string{data}}}
+	file := filepath.Join(data, "out.txt")
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{fmt.Sprintf(`writeFile(%q, "a"); appendFile(%q, "b"); return readFile(%q);`, file, file, file), "ab"},
+		{fmt.Sprintf(`return fileExists(%q);`, file), "true"},
+		{fmt.Sprintf(`removeFile(%q); return fileExists(%q);`, file, file), "false"},
+		{fmt.Sprintf(`return readFile(%q);`, filepath.Join(dir, "secret")),
+			fmt.Sprintf(`Permission denied: readFile cannot access %q.`, filepath.Join(dir, "secret"))},
+		{fmt.Sprintf(`return readFile(%q);`, filepath.Join(data, "..", "secret")),
+			fmt.Sprintf(`Permission denied: readFile cannot access %q.`, filepath.Join(data, "..", "secret"))},
+		{fmt.Sprintf(`return readFile(%q);`, filepath.Join(data, "link")),
+			fmt.Sprintf(`Permission denied: readFile cannot access %q.`, filepath.Join(data, "link"))},
+		{fmt.Sprintf(`writeFile(%q, "x");`, filepath.Join(dir, "new")),
+			fmt.Sprintf(`Permission denied: writeFile cannot access %q.`, filepath.Join(dir, "new"))},
+	}
+	for _, tt := range tests {
+		if got := eval(t, perms, tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+	missing := filepath.Join(data, "missing")
+	if got := eval(t, perms, fmt.Sprintf(`return readFile(%q);`, missing)); !strings.HasPrefix(got, "readFile: open ") {
+		t.Errorf("Expected the error of reading a missing file, got %s", got)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("LOX_ALLOWED", "yes")
+	perms := Permissions{Env: {Only: []string{"LOX_ALLOWED", "LOX_UNSET"}}}
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`return getenv("LOX_ALLOWED");`, "yes"},
+		{`return getenv("LOX_UNSET");`, "nil"},
+		{`setenv("LOX_ALLOWED", "changed"); return getenv("LOX_ALLOWED");`, "changed"},
+		{`return getenv("HOME");`, `Permission denied: getenv cannot access "HOME".`},
+	}
+	for _, tt := range tests {
+		if got := eval(t, perms, tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestNet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hello" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "hi")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	tests := []struct {
+		perms    Permissions
+		src      string
+		expected string
+	}{
+		{Permissions{Net: {}}, fmt.Sprintf(`return httpGet("%s/hello");`, server.URL), "hi"},
+		{Permissions{Net: {Only: []string{u.Hostname()}}}, fmt.Sprintf(`return httpGet("%s/hello");`, server.URL), "hi"},
+		{Permissions{Net: {Only: []string{u.Host}}}, fmt.Sprintf(`return httpGet("%s/hello");`, server.URL), "hi"},
+		{Permissions{Net: {}}, fmt.Sprintf(`return httpGet("%s/other");`, server.URL),
+			fmt.Sprintf(`httpGet: %s/other responded 404 Not Found.`, server.URL)},
+		{Permissions{Net: {Only: []string{"example.com"}}}, fmt.Sprintf(`return httpGet("%s/hello");`, server.URL),
+			fmt.Sprintf(`Permission denied: httpGet cannot access %q.`, u.Hostname())},
+		{Permissions{Net: {}}, `return httpGet("file:///etc/passwd");`, `httpGet: unsupported URL scheme "file".`},
+	}
+	for _, tt := range tests {
+		if got := eval(t, tt.perms, tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestNetRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hello":
+			fmt.Fprint(w, "hi")
+		case "/here":
+			http.Redirect(w, r, "/hello", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, "http://localhost:"+otherURL.Port()+"/", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	only := Permissions{Net: {Only: []string{u.Hostname()}}}
+	tests := []struct {
+		perms    Permissions
+		src      string
+		expected string
+	}{
+		{only, fmt.Sprintf(`return httpGet("%s/here");`, server.URL), "hi"},
+		{only, fmt.Sprintf(`return httpGet("%s/other");`, server.URL), `Permission denied: httpGet cannot access "localhost".`},
+		{Permissions{Net: {}}, fmt.Sprintf(`return httpGet("%s/other");`, server.URL), "secret"},
+		{Permissions{Net: {}}, fmt.Sprintf(`return httpGet("%s/file");`, server.URL), `httpGet: unsupported URL scheme "file".`},
+	}
+	for _, tt := range tests {
+		if got := eval(t, tt.perms, tt.src); got != tt.expected {
+			t.Errorf("Expected %s to give %s, got %s", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestSleepCancelled(t *testing.T) {
+	l := lexer.NewLexer(`sleep(60);`)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	intp := interp.New()
+	Install(&intp, Defaults())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	func() {
+		defer func() { recover() }()
+		intp.EvalContext(ctx, prog)
+	}()
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("Expected sleep to stop when the program is cancelled")
+	}
+}
+
+func TestNames(t *testing.T) {
+	intp := interp.New()
+	Install(&intp, nil)
+	names := Names()
+	if len(names) != len(builtins) || names[0] != "abs" {
+		t.Fatalf("Expected the sorted names of every builtin, got %v", names)
+	}
+	// builtins are declared even when denied
+	for _, name := range names {
+		if _, found := intp.Lookup(name); !found {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+}
@@ -21,6 +21,7 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	COLON
 
 	// One or two char tokens
 	BANG
@@ -40,11 +41,13 @@ const (
 	// Keywords
 	AND
 	CLASS
+	DEBUGGER
 	ELSE
 	FALSE
 	FUN
 	FOR
 	IF
+	IMPORT
 	NIL
 	OR
 	PRINT
@@ -55,6 +58,9 @@ const (
 	VAR
 	WHILE
 
+	// Trivia, only produced when the lexer keeps comments
+	COMMENT
+
 	EOF
 	INVALID
 )
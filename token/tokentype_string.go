@@ -19,40 +19,44 @@ func _() {
 	_ = x[SEMICOLON-8]
 	_ = x[SLASH-9]
 	_ = x[STAR-10]
-	_ = x[BANG-11]
-	_ = x[BANG_EQUAL-12]
-	_ = x[EQUAL-13]
-	_ = x[EQUAL_EQUAL-14]
-	_ = x[GREATER-15]
-	_ = x[GREATER_EQUAL-16]
-	_ = x[LESS-17]
-	_ = x[LESS_EQUAL-18]
-	_ = x[IDENTIFIER-19]
-	_ = x[STRING-20]
-	_ = x[NUMBER-21]
-	_ = x[AND-22]
-	_ = x[CLASS-23]
-	_ = x[ELSE-24]
-	_ = x[FALSE-25]
-	_ = x[FUN-26]
-	_ = x[FOR-27]
-	_ = x[IF-28]
-	_ = x[NIL-29]
-	_ = x[OR-30]
-	_ = x[PRINT-31]
-	_ = x[RETURN-32]
-	_ = x[SUPER-33]
-	_ = x[THIS-34]
-	_ = x[TRUE-35]
-	_ = x[VAR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
-	_ = x[INVALID-39]
+	_ = x[COLON-11]
+	_ = x[BANG-12]
+	_ = x[BANG_EQUAL-13]
+	_ = x[EQUAL-14]
+	_ = x[EQUAL_EQUAL-15]
+	_ = x[GREATER-16]
+	_ = x[GREATER_EQUAL-17]
+	_ = x[LESS-18]
+	_ = x[LESS_EQUAL-19]
+	_ = x[IDENTIFIER-20]
+	_ = x[STRING-21]
+	_ = x[NUMBER-22]
+	_ = x[AND-23]
+	_ = x[CLASS-24]
+	_ = x[DEBUGGER-25]
+	_ = x[ELSE-26]
+	_ = x[FALSE-27]
+	_ = x[FUN-28]
+	_ = x[FOR-29]
+	_ = x[IF-30]
+	_ = x[IMPORT-31]
+	_ = x[NIL-32]
+	_ = x[OR-33]
+	_ = x[PRINT-34]
+	_ = x[RETURN-35]
+	_ = x[SUPER-36]
+	_ = x[THIS-37]
+	_ = x[TRUE-38]
+	_ = x[VAR-39]
+	_ = x[WHILE-40]
+	_ = x[COMMENT-41]
+	_ = x[EOF-42]
+	_ = x[INVALID-43]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEEOFINVALID"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARCOLONBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSDEBUGGERELSEFALSEFUNFORIFIMPORTNILORPRINTRETURNSUPERTHISTRUEVARWHILECOMMENTEOFINVALID"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 217, 220, 225, 228, 235}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 82, 86, 96, 101, 112, 119, 132, 136, 146, 156, 162, 168, 171, 176, 184, 188, 193, 196, 199, 201, 207, 210, 212, 217, 223, 228, 232, 236, 239, 244, 251, 254, 261}
 
 func (i TokenType) String() string {
 	if i >= TokenType(len(_TokenType_index)-1) {
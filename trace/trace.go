@@ -0,0 +1,141 @@
+// Package trace prints what the interpreter is doing as an indented trace
+package trace
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/format"
+	"golox/obj"
+	"golox/token"
+	"io"
+	"strings"
+)
+
+// Printer is an interp.Tracer printing every statement, call, return,
+// variable binding and assignment, and runtime error, with its line.
+// Statements and calls nested in others are indented.
+type Printer struct {
+	w      io.Writer
+	lines  []int // lines of the statements being evaluated, innermost last
+	failed bool  // a runtime error is unwinding and has been printed
+}
+
+// NewPrinter returns a Printer writing to w
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Prints a message at the line and depth of the innermost statement
+func (p *Printer) printf(line int, format string, args ...interface{}) {
+	indent := strings.Repeat("  ", len(p.lines))
+	fmt.Fprintf(p.w, "%4d | %s%s\n", line+1, indent, fmt.Sprintf(format, args...))
+}
+
+// Returns the line of the innermost statement
+func (p *Printer) line() int {
+	if len(p.lines) == 0 {
+		return 0
+	}
+	return p.lines[len(p.lines)-1]
+}
+
+func (p *Printer) Enter(node ast.Node) {
+	stmt, isStmt := node.(ast.Stmt)
+	if !isStmt {
+		return
+	}
+	p.failed = false
+	line := ast.StmtToken(stmt).Line
+	p.printf(line, "%s", header(stmt))
+	p.lines = append(p.lines, line)
+}
+
+func (p *Printer) Exit(node ast.Node, val obj.Obj) {
+	if _, isStmt := node.(ast.Stmt); isStmt {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+}
+
+func (p *Printer) Error(node ast.Node, err interface{}) {
+	if !p.failed {
+		p.printf(p.line(), "error: %v", err)
+		p.failed = true
+	}
+	if _, isStmt := node.(ast.Stmt); isStmt {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+}
+
+func (p *Printer) Call(name string, call token.Token, args []obj.Obj) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = show(arg)
+	}
+	p.printf(call.Line, "call %s(%s)", name, strings.Join(strs, ", "))
+	// the function body is nested in the call
+	p.lines = append(p.lines, call.Line)
+}
+
+func (p *Printer) Return(name string, val obj.Obj) {
+	p.lines = p.lines[:len(p.lines)-1]
+	if !p.failed {
+		p.printf(p.line(), "return %s -> %s", name, show(val))
+	}
+}
+
+func (p *Printer) Bind(name string, val obj.Obj) {
+	p.printf(p.line(), "bind %s = %s", name, show(val))
+}
+
+func (p *Printer) Assign(name string, val obj.Obj) {
+	p.printf(p.line(), "assign %s = %s", name, show(val))
+}
+
+// Returns a value as shown in the trace, leaving out function bodies
+func show(val obj.Obj) string {
+	switch val := val.(type) {
+	case nil:
+		return "nil"
+	case *obj.Closure:
+		params := make([]string, len(val.Params))
+		for i, param := range val.Params {
+			params[i] = param.String()
+		}
+		return "fun(" + strings.Join(params, ", ") + ")"
+	}
+	return val.String()
+}
+
+// Returns the source of a statement, without the bodies of compound statements
+func header(stmt ast.Stmt) string {
+	switch stmt := stmt.(type) {
+	case *ast.ExprStmt:
+		return format.Expr(stmt.Expr) + ";"
+	case *ast.PrintStmt:
+		return "print " + format.Expr(stmt.Expr) + ";"
+	case *ast.AssignStmt:
+		return stmt.Name.String() + " = " + format.Expr(stmt.Expr) + ";"
+	case *ast.SetStmt:
+		return format.Expr(&ast.GetExpr{Object: stmt.Object, Name: stmt.Name}) + " = " + format.Expr(stmt.Value) + ";"
+	case *ast.VarStmt:
+		return "var " + stmt.Name.String() + ast.Annotation(stmt.Type) + " = " + format.Expr(stmt.Value) + ";"
+	case *ast.ReturnStmt:
+		if stmt.ReturnValue == nil {
+			return "return;"
+		}
+		return "return " + format.Expr(stmt.ReturnValue) + ";"
+	case *ast.FuncDeclStmt:
+		params := make([]string, len(stmt.Params))
+		for i, param := range stmt.Params {
+			params[i] = param.String() + ast.Annotation(stmt.ParamType(i))
+		}
+		return "fun " + stmt.Name.String() + "(" + strings.Join(params, ", ") + ")" + ast.Annotation(stmt.ReturnType)
+	case *ast.BlockStmt:
+		return "{"
+	case *ast.IfStmt:
+		return "if " + format.Expr(stmt.Cond)
+	case *ast.WhileStmt:
+		return "while " + format.Expr(stmt.Cond)
+	}
+	return stmt.String()
+}
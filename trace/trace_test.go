@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package trace
+
+import (
+	"bytes"
+	"golox/interp"
+	"golox/lexer"
+	"golox/parser"
+	"testing"
+)
+
+func trace(t *testing.T, src string) string {
+	l := lexer.NewLexer(src)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parse errors: %v", p.Errors())
+	}
+	var out bytes.Buffer
+	intp := interp.New()
+	intp.Tracer = NewPrinter(&out)
+	func() {
+		defer func() { recover() }()
+		intp.Eval(prog)
+	}()
+	return out.String()
+}
+
+func assertTrace(t *testing.T, got, want string) {
+	if got != want {
+		t.Fatalf("Expected trace:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	out := trace(t, `var total = 0;
+fun add(n) {
+    if (n > 0) {
+        total = total + n;
+    }
+}
+add(2);
+`)
+	assertTrace(t, out, `   1 | var total = 0;
+   1 |   bind total = 0.000000
+   2 | fun add(n)
+   2 |   bind add = fun(n)
+   7 | add(2);
+   7 |   call add(2.000000)
+   7 |     bind n = 2.000000
+   3 |     if n > 0
+   4 |       total = total + n;
+   4 |         assign total = 2.000000
+   7 |   return add -> nil
+`)
+}
+
+func TestPrinterError(t *testing.T) {
+	out := trace(t, `fun f() {
+    return nope;
+}
+var x = f();
+`)
+	assertTrace(t, out, `   1 | fun f()
+   1 |   bind f = fun()
+   4 | var x = f();
+   4 |   call f()
+   2 |     return nope;
+   2 |       error: Variable "nope" does not exist in this scope.
+`)
+}
@@ -0,0 +1,346 @@
+package types
+
+import (
+	"fmt"
+	"golox/ast"
+	"golox/scope"
+	"golox/token"
+	"sort"
+)
+
+// Error is a type mismatch found before running a program
+type Error struct {
+	Token token.Token // where the mismatch is
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[line %d:%d] %s", e.Token.Line, e.Token.LineOffset, e.Msg)
+}
+
+// Check infers the types of prog and returns every mismatch with its annotations,
+// and every operator, call or return that would fail at runtime, in source order.
+// Variables that are never assigned have the type of their initial value,
+// and those that are assigned have their annotated type, or Any.
+// prog must have been parsed without errors.
+func Check(prog *ast.Program) []*Error {
+	c := &checker{
+		info:    scope.Resolve(prog),
+		symbols: make(map[token.Token]*scope.Symbol),
+		types:   make(map[*scope.Symbol]Type),
+		assigns: make(map[token.Token]bool),
+	}
+	for _, sym := range c.info.Symbols {
+		c.symbols[sym.Decl] = sym
+		for _, ref := range sym.Refs {
+			c.symbols[ref] = sym
+		}
+	}
+	ast.Inspect(prog, func(n ast.Node) bool {
+		if assign, isAssign := n.(*ast.AssignStmt); isAssign {
+			c.assigns[assign.Name.Token] = true
+		}
+		return true
+	})
+	c.stmts(prog.Statements)
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Token, c.errors[j].Token
+		return a.Line < b.Line || a.Line == b.Line && a.LineOffset < b.LineOffset
+	})
+	return c.errors
+}
+
+type checker struct {
+	info    *scope.Info
+	symbols map[token.Token]*scope.Symbol // symbol of each declaration and use
+	types   map[*scope.Symbol]Type
+	assigns map[token.Token]bool // name tokens of assignment statements
+	fn      *function            // function whose body is being checked, nil at the top level
+	errors  []*Error
+}
+
+// A function whose body is being checked
+type function struct {
+	name     string
+	result   Type // annotated result type, or nil to infer it
+	returned Type // join of the types returned so far
+}
+
+func (c *checker) errorf(tok token.Token, format string, args ...interface{}) {
+	c.errors = append(c.errors, &Error{Token: tok, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Returns the type an annotation names, or nil if there is no annotation
+func (c *checker) annotation(typ *ast.Identifier) Type {
+	if typ == nil {
+		return nil
+	}
+	t := Lookup(typ.String())
+	if t == nil {
+		c.errorf(typ.Token, "Unknown type %q.", typ.String())
+		return Any
+	}
+	return t
+}
+
+// Returns if the symbol is assigned anywhere, so its type may change
+func (c *checker) isAssigned(sym *scope.Symbol) bool {
+	for _, ref := range sym.Refs {
+		if c.assigns[ref] {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets the type of the symbol declared by name, given the type of its value
+// and its annotated type, if any
+func (c *checker) declare(name *ast.Identifier, annotated Type, val Type) {
+	sym := c.symbols[name.Token]
+	if sym == nil {
+		return
+	}
+	switch {
+	case annotated != nil:
+		c.types[sym] = annotated
+	case c.isAssigned(sym):
+		c.types[sym] = Any
+	default:
+		c.types[sym] = val
+	}
+}
+
+// Returns the type of the symbol a name token resolves to
+func (c *checker) typeOf(tok token.Token) Type {
+	if sym := c.symbols[tok]; sym != nil {
+		if t, found := c.types[sym]; found {
+			return t
+		}
+	}
+	return Any
+}
+
+func (c *checker) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.stmt(stmt)
+	}
+}
+
+func (c *checker) block(bs *ast.BlockStmt) {
+	if bs != nil {
+		c.stmts(bs.Statements)
+	}
+}
+
+func (c *checker) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.ExprStmt:
+		c.expr(stmt.Expr)
+	case *ast.PrintStmt:
+		c.expr(stmt.Expr)
+	case *ast.AssignStmt:
+		val := c.expr(stmt.Expr)
+		if t := c.typeOf(stmt.Name.Token); !AssignableTo(val, t) {
+			c.errorf(stmt.Name.Token, "Cannot assign %s to %q of type %s.", val, stmt.Name, t)
+		}
+	case *ast.SetStmt:
+		c.object(stmt.Object, stmt.Name.Token)
+		c.expr(stmt.Value)
+	case *ast.VarStmt:
+		val := c.expr(stmt.Value)
+		annotated := c.annotation(stmt.Type)
+		if annotated != nil && !AssignableTo(val, annotated) {
+			c.errorf(stmt.Name.Token, "Cannot use %s as the value of %q of type %s.", val, stmt.Name, annotated)
+		}
+		c.declare(stmt.Name, annotated, val)
+	case *ast.ImportStmt:
+		// the globals of modules are not checked
+		c.declare(stmt.Name(), nil, Any)
+	case *ast.FuncDeclStmt:
+		c.funcDecl(stmt)
+	case *ast.BlockStmt:
+		c.block(stmt)
+	case *ast.IfStmt:
+		c.expr(stmt.Cond)
+		c.block(stmt.OnTrue)
+		c.block(stmt.OnFalse)
+	case *ast.WhileStmt:
+		c.expr(stmt.Cond)
+		c.block(stmt.Body)
+	case *ast.ReturnStmt:
+		var val Type = Nil
+		if stmt.ReturnValue != nil {
+			val = c.expr(stmt.ReturnValue)
+		}
+		if c.fn == nil {
+			return
+		}
+		if c.fn.result != nil && !AssignableTo(val, c.fn.result) {
+			c.errorf(stmt.Token, "Cannot return %s from %q, which returns %s.", val, c.fn.name, c.fn.result)
+		}
+		c.fn.returned = join(c.fn.returned, val)
+	}
+}
+
+func (c *checker) funcDecl(decl *ast.FuncDeclStmt) {
+	sig := &Func{Params: make([]Type, len(decl.Params))}
+	for i := range decl.Params {
+		if t := c.annotation(decl.ParamType(i)); t != nil {
+			sig.Params[i] = t
+		} else {
+			sig.Params[i] = Any
+		}
+	}
+	annotated := c.annotation(decl.ReturnType)
+	// until the body is checked, recursive calls return Any
+	sig.Result = Any
+	if annotated != nil {
+		sig.Result = annotated
+	}
+	c.declare(decl.Name, nil, sig)
+	for i, param := range decl.Params {
+		c.declare(param, sig.Params[i], Any)
+	}
+
+	outer := c.fn
+	c.fn = &function{name: decl.Name.String(), result: annotated}
+	c.block(decl.Body)
+	fn := c.fn
+	c.fn = outer
+
+	mayEnd := !returns(decl.Body)
+	if annotated != nil {
+		if mayEnd && !AssignableTo(Nil, annotated) {
+			c.errorf(decl.Name.Token, "Function %q may end without returning %s.", decl.Name, annotated)
+		}
+		return
+	}
+	if mayEnd {
+		fn.returned = join(fn.returned, Nil)
+	}
+	sig.Result = fn.returned
+}
+
+// Returns if a block always returns before its end
+func returns(bs *ast.BlockStmt) bool {
+	if bs == nil {
+		return false
+	}
+	for _, stmt := range bs.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStmt:
+			return true
+		case *ast.BlockStmt:
+			if returns(stmt) {
+				return true
+			}
+		case *ast.IfStmt:
+			if returns(stmt.OnTrue) && returns(stmt.OnFalse) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the type of an expression, reporting mismatches within it
+func (c *checker) expr(expr ast.Expr) Type {
+	switch expr := expr.(type) {
+	case ast.NumExpr:
+		return Num
+	case ast.StrExpr:
+		return Str
+	case ast.BoolExpr:
+		return Bool
+	case ast.NilExpr:
+		return Nil
+	case ast.Identifier:
+		return c.typeOf(expr.Token)
+	case *ast.PrefixExpr:
+		right := c.expr(expr.Right)
+		if expr.Token.Type == token.BANG {
+			return Bool
+		}
+		c.operand(expr.Token, right, Num)
+		return Num
+	case *ast.InfixExpr:
+		return c.infix(expr)
+	case *ast.CallExpr:
+		return c.call(expr)
+	case *ast.GetExpr:
+		c.object(expr.Object, expr.Name.Token)
+	case *ast.MethodCallExpr:
+		c.object(expr.Object, expr.Name.Token)
+		for _, arg := range expr.Args {
+			c.expr(arg)
+		}
+	}
+	return Any
+}
+
+// Reports an object expression whose type has no properties.
+// Objects come from the host program, so their properties are not checked.
+func (c *checker) object(expr ast.Expr, name token.Token) {
+	if t := c.expr(expr); t != Any && t != Obj {
+		c.errorf(name, "Cannot get property %q of %s.", name.Lexeme, t)
+	}
+}
+
+// Reports an operand of op that is not of the type expected
+func (c *checker) operand(op token.Token, t Type, expected Type) {
+	if !AssignableTo(t, expected) {
+		c.errorf(op, "Operator %q expects %s operands, got %s.", op.Lexeme, expected, t)
+	}
+}
+
+// Checks the operands of an infix expression as evalInfix would
+func (c *checker) infix(ie *ast.InfixExpr) Type {
+	left := c.expr(ie.Left)
+	right := c.expr(ie.Right)
+	switch ie.Token.Type {
+	case token.PLUS, token.MINUS, token.STAR, token.SLASH:
+		c.operand(ie.Token, left, Num)
+		c.operand(ie.Token, right, Num)
+		return Num
+	case token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL:
+		c.operand(ie.Token, left, Num)
+		c.operand(ie.Token, right, Num)
+		return Bool
+	case token.AND, token.OR:
+		c.operand(ie.Token, left, Bool)
+		c.operand(ie.Token, right, Bool)
+		return Bool
+	case token.EQUAL_EQUAL, token.BANG_EQUAL:
+		if isFunc(left) {
+			c.errorf(ie.Token, "Cannot compare functions with %q.", ie.Token.Lexeme)
+		}
+		return Bool
+	}
+	return Any
+}
+
+// Checks the arguments of a call against the function's parameters
+func (c *checker) call(ce *ast.CallExpr) Type {
+	args := make([]Type, len(ce.Args))
+	for i, arg := range ce.Args {
+		args[i] = c.expr(arg)
+	}
+	switch fn := c.typeOf(ce.Token).(type) {
+	case *Func:
+		if len(args) != len(fn.Params) {
+			c.errorf(ce.Token, "Function %q expects %d arguments, got %d.", ce.Token.Lexeme, len(fn.Params), len(args))
+			return fn.Result
+		}
+		for i, arg := range args {
+			if !AssignableTo(arg, fn.Params[i]) {
+				c.errorf(ce.Token, "Argument %d of %q must be %s, got %s.", i+1, ce.Token.Lexeme, fn.Params[i], arg)
+			}
+		}
+		return fn.Result
+	case Basic:
+		if fn != Any && fn != Fun {
+			c.errorf(ce.Token, "Cannot call %q of type %s.", ce.Token.Lexeme, fn)
+		}
+	}
+	return Any
+}
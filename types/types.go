@@ -0,0 +1,93 @@
+// Package types checks the type annotations of Lox programs before they run.
+// Types are inferred where there are no annotations, and anything that
+// cannot be inferred is Any, which is never reported.
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the static type of a Lox value
+type Type interface {
+	String() string
+}
+
+// Basic is a type named by an annotation
+type Basic string
+
+const (
+	Any  Basic = "Any"
+	Num  Basic = "Num"
+	Str  Basic = "Str"
+	Bool Basic = "Bool"
+	Nil  Basic = "Nil"
+	Fun  Basic = "Fun" // any function
+	Obj  Basic = "Obj" // an object of the host program
+)
+
+func (b Basic) String() string { return string(b) }
+
+// Func is the type of a declared function
+type Func struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Func) String() string {
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("Fun(%s): %s", strings.Join(params, ", "), f.Result)
+}
+
+// Lookup returns the type an annotation names, or nil if there is none
+func Lookup(name string) Type {
+	switch b := Basic(name); b {
+	case Any, Num, Str, Bool, Nil, Fun, Obj:
+		return b
+	}
+	return nil
+}
+
+// AssignableTo returns if a value of type from can be used where to is expected
+func AssignableTo(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+	fromFunc, fromIsFunc := from.(*Func)
+	if to == Fun {
+		return fromIsFunc || from == Fun
+	}
+	toFunc, toIsFunc := to.(*Func)
+	if fromIsFunc && toIsFunc {
+		if len(fromFunc.Params) != len(toFunc.Params) {
+			return false
+		}
+		for i := range fromFunc.Params {
+			if !AssignableTo(toFunc.Params[i], fromFunc.Params[i]) {
+				return false
+			}
+		}
+		return AssignableTo(fromFunc.Result, toFunc.Result)
+	}
+	return from == to
+}
+
+// Returns the type of a value that is either of a or b
+func join(a, b Type) Type {
+	if a == nil {
+		return b
+	}
+	if a == b || a.String() == b.String() {
+		return a
+	}
+	return Any
+}
+
+// Returns if a value of the type is a function
+func isFunc(t Type) bool {
+	_, isFunc := t.(*Func)
+	return isFunc || t == Fun
+}
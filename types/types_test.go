@@ -0,0 +1,154 @@
+//go:build unit
+// +build unit
+
+package types
+
+import (
+	"golox/lexer"
+	"golox/parser"
+	"strings"
+	"testing"
+)
+
+func testCheck(t *testing.T, source string, expected ...string) {
+	t.Helper()
+	l := lexer.NewLexer(source)
+	p := parser.New(&l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Unexpected parse errors: %v", p.Errors())
+	}
+	errs := Check(prog)
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Error()
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected errors:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestCheckAnnotations(t *testing.T) {
+	testCheck(t, `var x: Num = "a";
+var y: Any = "a";
+var z: Foo = 1;
+var n: Num = 1;
+n = true;
+var f: Fun = n;`,
+		`[line 0:4] Cannot use Str as the value of "x" of type Num.`,
+		`[line 2:7] Unknown type "Foo".`,
+		`[line 4:0] Cannot assign Bool to "n" of type Num.`,
+		`[line 5:4] Cannot use Num as the value of "f" of type Fun.`,
+	)
+}
+
+func TestCheckOperators(t *testing.T) {
+	testCheck(t, `var s = "s";
+print s + 1;
+print 1 < s;
+print -s;
+print !s;
+print s and true;
+print s == 1;
+fun f() {}
+print f == f;`,
+		`[line 1:8] Operator "+" expects Num operands, got Str.`,
+		`[line 2:8] Operator "<" expects Num operands, got Str.`,
+		`[line 3:6] Operator "-" expects Num operands, got Str.`,
+		`[line 5:8] Operator "and" expects Bool operands, got Str.`,
+		`[line 8:8] Cannot compare functions with "==".`,
+	)
+}
+
+func TestCheckCalls(t *testing.T) {
+	testCheck(t, `fun f(a: Str, b: Num): Bool { return true; }
+f(1);
+f(1, "b");
+f("a", 1) + 1;
+var x = 1;
+x();
+g(1);`,
+		`[line 1:0] Function "f" expects 2 arguments, got 1.`,
+		`[line 2:0] Argument 1 of "f" must be Str, got Num.`,
+		`[line 2:0] Argument 2 of "f" must be Num, got Str.`,
+		`[line 3:10] Operator "+" expects Num operands, got Bool.`,
+		`[line 5:0] Cannot call "x" of type Num.`,
+	)
+}
+
+func TestCheckProperties(t *testing.T) {
+	testCheck(t, `var n = 1;
+print n.x;
+n.x = 2;
+n.m(1 + "a");
+fun f(o: Obj) { o.x = o.m(); }
+print request.x;`,
+		`[line 1:8] Cannot get property "x" of Num.`,
+		`[line 2:2] Cannot get property "x" of Num.`,
+		`[line 3:2] Cannot get property "m" of Num.`,
+		`[line 3:6] Operator "+" expects Num operands, got Str.`,
+	)
+}
+
+func TestCheckReturns(t *testing.T) {
+	testCheck(t, `fun f(n: Num): Num {
+    if (n > 1) {
+        return "big";
+    }
+}
+fun g(n): Nil {
+    return;
+}
+fun h(n) {
+    if (n) { return 1; } else { return 2; }
+}
+fun i(n) {
+    if (n) { return 1; }
+}
+print h(1) + 1;
+print i(1) + 1;
+return "top level";`,
+		`[line 0:4] Function "f" may end without returning Num.`,
+		`[line 2:8] Cannot return Str from "f", which returns Num.`,
+	)
+}
+
+func TestCheckInference(t *testing.T) {
+	testCheck(t, `fun double(n) { return n * 2; }
+var d = double(2);
+print d and true;
+var changing = 1;
+changing = "now a string";
+print changing + 1;
+fun fact(n: Num): Num {
+    if (n < 1) { return 1; }
+    return n * fact(n - 1);
+}
+print fact(3) or false;`,
+		`[line 2:8] Operator "and" expects Bool operands, got Num.`,
+		`[line 10:14] Operator "or" expects Bool operands, got Num.`,
+	)
+}
+
+func TestAssignableTo(t *testing.T) {
+	f := &Func{Params: []Type{Num}, Result: Str}
+	cases := []struct {
+		from, to Type
+		expected bool
+	}{
+		{Num, Num, true},
+		{Num, Str, false},
+		{Any, Num, true},
+		{Str, Any, true},
+		{f, Fun, true},
+		{Num, Fun, false},
+		{f, &Func{Params: []Type{Str}, Result: Str}, false},
+		{f, &Func{Params: []Type{Num}, Result: Any}, true},
+		{f, &Func{Params: []Type{Num, Num}, Result: Str}, false},
+	}
+	for _, c := range cases {
+		if AssignableTo(c.from, c.to) != c.expected {
+			t.Errorf("Expected AssignableTo(%s, %s) to be %t", c.from, c.to, c.expected)
+		}
+	}
+}